@@ -5,8 +5,12 @@ import (
 	"bicycle-store/internal/database"
 	"bicycle-store/internal/middleware"
 	"bicycle-store/internal/routes"
+	"bicycle-store/internal/services"
 	_ "bicycle-store/docs"
+	"context"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,6 +32,10 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
@@ -41,9 +49,72 @@ func main() {
 	}
 	defer database.Disconnect()
 
+	// Build the in-memory autocomplete index
+	if err := services.NewSearchService().RebuildIndex(context.Background()); err != nil {
+		log.Printf("Warning: Failed to build search index: %v", err)
+	}
+
+	// Imports run in-process, so those of a previous run will never finish
+	if failed, err := services.NewCatalogService().FailInterruptedImports(context.Background()); err != nil {
+		log.Printf("Warning: Failed to fail interrupted imports: %v", err)
+	} else if failed > 0 {
+		log.Printf("Failed %d imports interrupted by a restart", failed)
+	}
+
+	// Permanently remove old archived bicycles once a day
+	bicycleService := services.NewBicycleService()
+	go runPeriodically("archive purge", 24*time.Hour, func(ctx context.Context) error {
+		purged, err := bicycleService.PurgeArchived(ctx, cfg.ArchiveRetention)
+		if purged > 0 {
+			log.Printf("Purged %d archived bicycles", purged)
+		}
+		return err
+	})
+
+	// Apply scheduled catalog changes as they become due
+	scheduledChangeService := services.NewScheduledChangeService()
+	go runPeriodically("scheduled changes", time.Minute, func(ctx context.Context) error {
+		applied, err := scheduledChangeService.ApplyDue(ctx)
+		if applied > 0 {
+			log.Printf("Applied %d scheduled catalog changes", applied)
+		}
+		return err
+	})
+
+	// Queue back-in-stock notifications missed after a stock increase
+	wishlistService := services.NewWishlistService()
+	go runPeriodically("stock alerts", 10*time.Minute, func(ctx context.Context) error {
+		queued, err := wishlistService.QueueStockAlerts(ctx)
+		if queued > 0 {
+			log.Printf("Queued %d back-in-stock notifications", queued)
+		}
+		return err
+	})
+
+	// Recompute related bicycles from the catalog and order history
+	recommendationService := services.NewRecommendationService()
+	go runPeriodically("recommendations", time.Hour, func(ctx context.Context) error {
+		count, err := recommendationService.Refresh(ctx)
+		if err == nil {
+			log.Printf("Refreshed recommendations of %d bicycles", count)
+		}
+		return err
+	})
+
 	// Create Gin router
 	router := gin.New()
 
+	// Client IPs key the rate limits, so forwarded addresses are only taken from known proxies
+	var trustedProxies []string
+	if cfg.TrustedProxies != "" {
+		for _, proxy := range strings.Split(cfg.TrustedProxies, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Middleware
 	router.Use(gin.Logger())
 	router.Use(middleware.RecoveryHandler())
@@ -58,6 +129,9 @@ func main() {
 		})
 	})
 
+	// Uploaded product images
+	router.Static("/uploads", cfg.UploadDir)
+
 	// Setup routes
 	routes.SetupRoutes(router)
 
@@ -67,3 +141,16 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// runPeriodically runs job now and then every interval for the life of the process
+func runPeriodically(name string, interval time.Duration, job func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(context.Background()); err != nil {
+			log.Printf("Warning: %s failed: %v", name, err)
+		}
+		<-ticker.C
+	}
+}
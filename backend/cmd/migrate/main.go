@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bicycle-store/internal/config"
+	"bicycle-store/internal/database"
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"bicycle-store/internal/services"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Data migrations for existing databases. Each migration is safe to run more than once.
+//
+//	go run cmd/migrate/main.go <migration> [flags]
+var migrations = map[string]func(ctx context.Context, args []string) error{
+	"variants":            migrateVariants,
+	"specifications":      migrateSpecifications,
+	"category_tree":       migrateCategoryTree,
+	"bicycle_status":      migrateBicycleStatus,
+	"review_verification": migrateReviewVerification,
+	"ratings":             migrateRatings,
+	"reviews":             migrateReviews,
+}
+
+func main() {
+	if len(os.Args) < 2 || migrations[os.Args[1]] == nil {
+		fmt.Println("Usage: go run cmd/migrate/main.go <migration> [flags]")
+		fmt.Println("Migrations:")
+		for name := range migrations {
+			fmt.Println("  " + name)
+		}
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg := config.LoadConfig()
+
+	// Connect to MongoDB
+	if err := database.Connect(cfg.MongoURI, cfg.DBName); err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer database.Disconnect()
+
+	name := os.Args[1]
+	log.Printf("Running migration %s...", name)
+	if err := migrations[name](context.Background(), os.Args[2:]); err != nil {
+		log.Fatalf("Migration %s failed: %v", name, err)
+	}
+	log.Printf("Migration %s completed", name)
+}
+
+// migrateVariants adds an empty variants array to bicycles created before variants
+// existed, so they keep selling as single products. With -generate it also creates
+// one variant per combination of the listed options, with zero stock to be filled in
+// through PATCH /bicycles/:id/stock.
+func migrateVariants(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("variants", flag.ExitOnError)
+	generate := flags.String("generate", "", "comma-separated option names to generate variants for, e.g. frame_color,wheel_size")
+	flags.Parse(args)
+
+	collection := database.GetCollection("bicycles")
+
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"variants": nil},
+		bson.M{"$set": bson.M{"variants": []models.Variant{}}},
+	)
+	if err != nil {
+		return err
+	}
+	log.Printf("Initialized variants on %d bicycles", result.ModifiedCount)
+
+	if *generate == "" {
+		return nil
+	}
+	axes := strings.Split(*generate, ",")
+
+	cursor, err := collection.Find(ctx, bson.M{"variants": bson.M{"$size": 0}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var bicycles []models.Bicycle
+	if err := cursor.All(ctx, &bicycles); err != nil {
+		return err
+	}
+
+	for _, bicycle := range bicycles {
+		variants := generateVariants(bicycle, axes)
+		if len(variants) == 0 {
+			continue
+		}
+
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": bicycle.ID, "variants": bson.M{"$size": 0}},
+			bson.M{"$set": bson.M{
+				"variants":       variants,
+				"stock_quantity": 0,
+				"updated_at":     time.Now(),
+			}},
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Generated %d variants for %s (previous stock %d must be assigned to variants)",
+			len(variants), bicycle.ModelName, bicycle.StockQuantity)
+	}
+
+	return nil
+}
+
+// migrateSpecifications converts weight, max load and wheel size from free text
+// like "13.5 kg" or "700C" to measurements in kilograms and inches. Text that
+// can't be parsed is kept as the label with a zero value and logged for review.
+func migrateSpecifications(ctx context.Context, args []string) error {
+	collection := database.GetCollection("bicycles")
+
+	fields := map[string]func(models.Measurement) (models.Measurement, error){
+		"weight":     models.NormalizeMass,
+		"max_load":   models.NormalizeMass,
+		"wheel_size": models.NormalizeWheelSize,
+	}
+
+	conditions := []bson.M{}
+	for field := range fields {
+		conditions = append(conditions, bson.M{"specifications." + field: bson.M{"$type": "string"}})
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"$or": conditions})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	converted := 0
+	for cursor.Next(ctx) {
+		var bicycle struct {
+			ID             primitive.ObjectID `bson:"_id"`
+			ModelName      string             `bson:"model_name"`
+			Specifications bson.M             `bson:"specifications"`
+		}
+		if err := cursor.Decode(&bicycle); err != nil {
+			return err
+		}
+
+		set := bson.M{}
+		for field, normalize := range fields {
+			text, ok := bicycle.Specifications[field].(string)
+			if !ok {
+				continue
+			}
+
+			measurement, err := models.ParseMeasurement(text)
+			if err == nil {
+				measurement, err = normalize(measurement)
+			}
+			if err != nil {
+				log.Printf("Could not parse %s %q of %s, kept as label: %v", field, text, bicycle.ModelName, err)
+				measurement = models.Measurement{Label: text}
+			}
+			set["specifications."+field] = measurement
+		}
+
+		set["updated_at"] = time.Now()
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": bicycle.ID}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+		converted++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	log.Printf("Converted specifications of %d bicycles", converted)
+	return nil
+}
+
+// migrateCategoryTree turns flat categories into top-level tree nodes and gives
+// each category without a slug one generated from its name, made unique with a
+// numeric suffix when needed
+func migrateCategoryTree(ctx context.Context, args []string) error {
+	collection := database.GetCollection("categories")
+
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"ancestors": nil},
+		bson.M{"$set": bson.M{"ancestors": []primitive.ObjectID{}, "parent_id": nil}},
+	)
+	if err != nil {
+		return err
+	}
+	log.Printf("Made %d categories top-level", result.ModifiedCount)
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var categories []models.Category
+	if err := cursor.All(ctx, &categories); err != nil {
+		return err
+	}
+
+	taken := make(map[string]bool)
+	for _, category := range categories {
+		if category.Slug != "" {
+			taken[category.Slug] = true
+		}
+	}
+
+	for _, category := range categories {
+		if category.Slug != "" {
+			continue
+		}
+
+		base := services.Slugify(category.Name)
+		if base == "" {
+			base = "category"
+		}
+		slug := base
+		for n := 2; taken[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[slug] = true
+
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": category.ID},
+			bson.M{"$set": bson.M{"slug": slug, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Category %s gets slug %s", category.Name, slug)
+	}
+
+	return nil
+}
+
+// migrateBicycleStatus marks bicycles created before statuses existed as active
+func migrateBicycleStatus(ctx context.Context, args []string) error {
+	result, err := database.GetCollection("bicycles").UpdateMany(ctx,
+		bson.M{"status": nil},
+		bson.M{"$set": bson.M{"status": models.BicycleStatusActive}},
+	)
+	if err != nil {
+		return err
+	}
+	log.Printf("Marked %d bicycles active", result.ModifiedCount)
+	return nil
+}
+
+// migrateReviewVerification marks existing reviews as verified purchases when their
+// author has a delivered order of the bicycle
+func migrateReviewVerification(ctx context.Context, args []string) error {
+	cursor, err := database.GetCollection("orders").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": "delivered"}}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$group", Value: bson.M{"_id": bson.M{
+			"customer_id": "$customer_id",
+			"bicycle_id":  "$items.bicycle_id",
+		}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var purchases []struct {
+		ID struct {
+			CustomerID primitive.ObjectID `bson:"customer_id"`
+			BicycleID  primitive.ObjectID `bson:"bicycle_id"`
+		} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &purchases); err != nil {
+		return err
+	}
+
+	verified := 0
+	reviewRepo := repositories.NewReviewRepository()
+	for _, purchase := range purchases {
+		result, err := database.GetCollection("reviews").UpdateMany(ctx,
+			bson.M{
+				"bicycle_id":        purchase.ID.BicycleID,
+				"customer_id":       purchase.ID.CustomerID,
+				"verified_purchase": bson.M{"$ne": true},
+			},
+			bson.M{"$set": bson.M{"verified_purchase": true}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		verified += int(result.ModifiedCount)
+
+		// Top reviews are copies and show the badge too
+		if err := reviewRepo.RefreshTopReviews(ctx, purchase.ID.BicycleID); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Marked %d reviews as verified purchases", verified)
+	return nil
+}
+
+// migrateRatings recomputes the rating aggregates of every bicycle from its approved reviews
+func migrateRatings(ctx context.Context, args []string) error {
+	histogram := bson.M{}
+	for star := 1; star <= 5; star++ {
+		histogram[fmt.Sprint(star)] = bson.M{"$size": bson.M{"$filter": bson.M{
+			"input": "$_approved",
+			"cond":  bson.M{"$eq": bson.A{"$$this.rating", star}},
+		}}}
+	}
+
+	collection := database.GetCollection("bicycles")
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "reviews",
+			"localField":   "_id",
+			"foreignField": "bicycle_id",
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"status": models.ReviewStatusApproved}},
+				bson.M{"$project": bson.M{"rating": 1}},
+			},
+			"as": "_approved",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"rating_count":     bson.M{"$size": "$_approved"},
+			"rating_sum":       bson.M{"$sum": "$_approved.rating"},
+			"rating_histogram": histogram,
+		}}},
+		{{Key: "$set", Value: bson.M{"rating_avg": repositories.RatingAverage("$rating_sum", "$rating_count")}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           "bicycles",
+			"on":             "_id",
+			"whenMatched":    "merge",
+			"whenNotMatched": "discard",
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	cursor.Close(ctx)
+
+	count, err := collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	log.Printf("Recalculated ratings of %d bicycles", count)
+	return nil
+}
+
+// migrateReviews moves the reviews embedded in bicycles to the reviews collection and
+// leaves each bicycle with its top reviews. Reviews written before moderation existed
+// are approved, as they were shown publicly. The old endpoint let a customer review a
+// bicycle more than once; only the latest of those reviews is kept.
+func migrateReviews(ctx context.Context, args []string) error {
+	bicycles := database.GetCollection("bicycles")
+	embedded := bson.M{"reviews": bson.M{"$exists": true}}
+
+	cursor, err := bicycles.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: embedded}},
+		{{Key: "$unwind", Value: "$reviews"}},
+		{{Key: "$sort", Value: bson.D{{Key: "reviews.review_date", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"bicycle_id": "$_id", "customer_id": "$reviews.customer_id"},
+			"review": bson.M{"$first": "$reviews"},
+		}}},
+		{{Key: "$replaceWith", Value: bson.M{"$mergeObjects": bson.A{
+			"$review",
+			bson.M{
+				"_id":               "$review.review_id",
+				"bicycle_id":        "$_id.bicycle_id",
+				"verified_purchase": bson.M{"$ifNull": bson.A{"$review.verified_purchase", false}},
+				"helpful_count":     0,
+				"status":            bson.M{"$ifNull": bson.A{"$review.status", models.ReviewStatusApproved}},
+			},
+		}}}},
+		{{Key: "$unset", Value: "review_id"}},
+		// Matched on the unique index, so reviews moved by an earlier run, or written
+		// since, are kept as they are now
+		{{Key: "$merge", Value: bson.M{
+			"into":           "reviews",
+			"on":             bson.A{"bicycle_id", "customer_id"},
+			"whenMatched":    "keepExisting",
+			"whenNotMatched": "insert",
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	cursor.Close(ctx)
+
+	var moved []struct {
+		ID      primitive.ObjectID `bson:"_id"`
+		Reviews []struct {
+			CustomerID primitive.ObjectID `bson:"customer_id"`
+		} `bson:"reviews"`
+	}
+	cursor, err = bicycles.Find(ctx, embedded, options.Find().SetProjection(bson.M{"reviews.customer_id": 1}))
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(ctx, &moved); err != nil {
+		return err
+	}
+
+	// Embedded reviews are only removed once every reviewer of the bicycle has a review
+	// in the collection
+	reviews := database.GetCollection("reviews")
+	for _, bicycle := range moved {
+		customers := make(map[primitive.ObjectID]bool)
+		for _, review := range bicycle.Reviews {
+			customers[review.CustomerID] = true
+		}
+		customerIDs := make([]primitive.ObjectID, 0, len(customers))
+		for id := range customers {
+			customerIDs = append(customerIDs, id)
+		}
+		count, err := reviews.CountDocuments(ctx, bson.M{"bicycle_id": bicycle.ID, "customer_id": bson.M{"$in": customerIDs}})
+		if err != nil {
+			return err
+		}
+		if count != int64(len(customerIDs)) {
+			return fmt.Errorf("bicycle %s: %d of %d reviewers have a review in the reviews collection", bicycle.ID.Hex(), count, len(customerIDs))
+		}
+	}
+
+	reviewRepo := repositories.NewReviewRepository()
+	for _, bicycle := range moved {
+		if err := reviewRepo.RefreshTopReviews(ctx, bicycle.ID); err != nil {
+			return err
+		}
+		if _, err := bicycles.UpdateOne(ctx, bson.M{"_id": bicycle.ID}, bson.M{"$unset": bson.M{"reviews": ""}}); err != nil {
+			return err
+		}
+	}
+
+	// Bicycles that never had reviews
+	if _, err := bicycles.UpdateMany(ctx,
+		bson.M{"top_reviews": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"top_reviews": bson.A{}}},
+	); err != nil {
+		return err
+	}
+
+	log.Printf("Moved the reviews of %d bicycles to the reviews collection", len(moved))
+	return nil
+}
+
+// generateVariants builds the cartesian product of the given options. Bicycles
+// that don't offer every option are skipped.
+func generateVariants(bicycle models.Bicycle, axes []string) []models.Variant {
+	combinations := [][]models.SelectedCustomization{{}}
+
+	for _, axis := range axes {
+		var values []string
+		for _, option := range bicycle.CustomizationOptions {
+			if option.Name == axis {
+				values = option.Options
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+
+		var next [][]models.SelectedCustomization
+		for _, combination := range combinations {
+			for _, value := range values {
+				options := append(append([]models.SelectedCustomization{}, combination...), models.SelectedCustomization{Name: axis, Value: value})
+				next = append(next, options)
+			}
+		}
+		combinations = next
+	}
+
+	variants := make([]models.Variant, 0, len(combinations))
+	for _, options := range combinations {
+		parts := []string{bicycle.Brand, bicycle.ModelName}
+		for _, option := range options {
+			parts = append(parts, option.Value)
+		}
+
+		variants = append(variants, models.Variant{
+			VariantID:     primitive.NewObjectID(),
+			SKU:           skuFrom(parts),
+			Options:       options,
+			StockQuantity: 0,
+		})
+	}
+	return variants
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Z0-9]+`)
+
+func skuFrom(parts []string) string {
+	for i, part := range parts {
+		parts[i] = strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToUpper(part), "-"), "-")
+	}
+	return strings.Join(parts, "-")
+}
@@ -7,6 +7,7 @@ import (
 	"bicycle-store/internal/utils"
 	"context"
 	"log"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -31,12 +32,15 @@ func main() {
 	database.GetCollection("bicycles").Drop(ctx)
 	database.GetCollection("customers").Drop(ctx)
 	database.GetCollection("orders").Drop(ctx)
+	database.GetCollection("reviews").Drop(ctx)
 
 	// Seed Categories
 	categories := []models.Category{
 		{
 			ID:          primitive.NewObjectID(),
 			Name:        "Mountain Bike",
+			Slug:        "mountain-bike",
+			Ancestors:   []primitive.ObjectID{},
 			Description: "Off-road bicycles with suspension and durable frames for trail riding",
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
@@ -44,6 +48,8 @@ func main() {
 		{
 			ID:          primitive.NewObjectID(),
 			Name:        "Road Bike",
+			Slug:        "road-bike",
+			Ancestors:   []primitive.ObjectID{},
 			Description: "Lightweight bicycles designed for paved roads and racing",
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
@@ -51,6 +57,8 @@ func main() {
 		{
 			ID:          primitive.NewObjectID(),
 			Name:        "City Bike",
+			Slug:        "city-bike",
+			Ancestors:   []primitive.ObjectID{},
 			Description: "Comfortable bicycles for urban commuting and casual rides",
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
@@ -58,17 +66,50 @@ func main() {
 		{
 			ID:          primitive.NewObjectID(),
 			Name:        "BMX",
+			Slug:        "bmx",
+			Ancestors:   []primitive.ObjectID{},
 			Description: "Compact bikes for tricks, racing, and freestyle riding",
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+			Attributes: []models.AttributeDefinition{
+				{Name: "discipline", Label: "Discipline", Type: models.AttributeString, Required: true, AllowedValues: []string{"Freestyle", "Race", "Dirt"}},
+				{Name: "rotor_system", Label: "Gyro rotor", Type: models.AttributeBoolean},
+			},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
 		},
 		{
 			ID:          primitive.NewObjectID(),
 			Name:        "Electric Bike",
+			Slug:        "electric-bike",
+			Ancestors:   []primitive.ObjectID{},
 			Description: "Motor-assisted bicycles for easier commuting and longer distances",
+			Attributes: []models.AttributeDefinition{
+				{Name: "battery_capacity", Label: "Battery capacity", Type: models.AttributeNumber, Unit: "Wh", Required: true},
+				{Name: "motor_power", Label: "Motor power", Type: models.AttributeInteger, Unit: "W", Required: true},
+				{Name: "range", Label: "Range", Type: models.AttributeNumber, Unit: "km"},
+				{Name: "motor_position", Label: "Motor position", Type: models.AttributeString, AllowedValues: []string{"Hub", "Mid-drive"}},
+			},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+	}
+
+	// Mountain bike subcategories
+	mountainID := categories[0].ID
+	for _, sub := range []struct{ name, description string }{
+		{"Trail", "All-round mountain bikes for mixed climbing and descending"},
+		{"Enduro", "Long-travel mountain bikes built for technical descents"},
+		{"XC", "Light cross-country mountain bikes for speed and climbing"},
+	} {
+		categories = append(categories, models.Category{
+			ID:          primitive.NewObjectID(),
+			Name:        sub.name,
+			Slug:        "mountain-bike-" + strings.ToLower(sub.name),
+			ParentID:    &mountainID,
+			Ancestors:   []primitive.ObjectID{mountainID},
+			Description: sub.description,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
-		},
+		})
 	}
 
 	categoryDocs := make([]interface{}, len(categories))
@@ -89,31 +130,28 @@ func main() {
 			CategoryID:    categories[0].ID, // Mountain Bike
 			Specifications: models.Specifications{
 				FrameMaterial: "Aluminum",
-				WheelSize:     "29 inches",
+				WheelSize:     models.Measurement{Value: 29, Unit: models.UnitInch},
 				GearCount:     21,
 				BrakeType:     "Hydraulic Disc",
 				Suspension:    "Front Suspension",
-				Weight:        "13.5 kg",
-				MaxLoad:       "120 kg",
+				Weight:        models.Measurement{Value: 13.5, Unit: models.UnitKilogram},
+				MaxLoad:       models.Measurement{Value: 120, Unit: models.UnitKilogram},
 			},
 			CustomizationOptions: []models.CustomizationOption{
-				{Name: "frame_color", Options: []string{"Red", "Blue", "Black", "Green"}},
-				{Name: "saddle_type", Options: []string{"Sport", "Comfort", "Racing"}},
-				{Name: "accessories", Options: []string{"Water Bottle Holder", "Phone Mount", "LED Light"}},
+				{Name: "frame_color", Options: []string{"Red", "Blue", "Black", "Green"}, Required: true},
+				{Name: "saddle_type", Options: []string{"Sport", "Comfort", "Racing"}, Surcharges: map[string]float64{"Racing": 12000}},
+				{Name: "accessories", Options: []string{"Water Bottle Holder", "Phone Mount", "LED Light"}, Multiple: true, Surcharges: map[string]float64{"Phone Mount": 5000, "LED Light": 8000}},
+			},
+			Variants: []models.Variant{
+				{VariantID: primitive.NewObjectID(), SKU: "RR-TBP29-RED", Options: []models.SelectedCustomization{{Name: "frame_color", Value: "Red"}}, StockQuantity: 5, Barcode: "4601234500011"},
+				{VariantID: primitive.NewObjectID(), SKU: "RR-TBP29-BLU", Options: []models.SelectedCustomization{{Name: "frame_color", Value: "Blue"}}, StockQuantity: 4, Barcode: "4601234500028"},
+				{VariantID: primitive.NewObjectID(), SKU: "RR-TBP29-BLK", Options: []models.SelectedCustomization{{Name: "frame_color", Value: "Black"}}, StockQuantity: 6, Barcode: "4601234500035"},
+				{VariantID: primitive.NewObjectID(), SKU: "RR-TBP29-GRN", Options: []models.SelectedCustomization{{Name: "frame_color", Value: "Green"}}, StockQuantity: 0, Barcode: "4601234500042"},
 			},
 			Description: "Professional mountain bike for trail riding with excellent suspension",
 			ImageURL:    "/images/trailblazer-pro-29.jpg",
-			Reviews: []models.Review{
-				{
-					ReviewID:     primitive.NewObjectID(),
-					CustomerName: "Aidos Bekzhanov",
-					Rating:       5,
-					Comment:      "Excellent bike for mountain trails! Great suspension.",
-					ReviewDate:   time.Now().AddDate(0, -1, 0),
-				},
-			},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
 		},
 		{
 			ID:            primitive.NewObjectID(),
@@ -124,12 +162,12 @@ func main() {
 			CategoryID:    categories[1].ID, // Road Bike
 			Specifications: models.Specifications{
 				FrameMaterial: "Carbon Fiber",
-				WheelSize:     "700C",
+				WheelSize:     models.Measurement{Value: 28, Unit: models.UnitInch, Label: "700C"},
 				GearCount:     22,
 				BrakeType:     "Caliper Brakes",
 				Suspension:    "None",
-				Weight:        "8.2 kg",
-				MaxLoad:       "100 kg",
+				Weight:        models.Measurement{Value: 8.2, Unit: models.UnitKilogram},
+				MaxLoad:       models.Measurement{Value: 100, Unit: models.UnitKilogram},
 			},
 			CustomizationOptions: []models.CustomizationOption{
 				{Name: "frame_color", Options: []string{"White", "Red", "Black"}},
@@ -138,7 +176,6 @@ func main() {
 			},
 			Description: "Lightweight carbon road bike for competitive racing",
 			ImageURL:    "/images/speedmaster-700c.jpg",
-			Reviews:     []models.Review{},
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
@@ -151,12 +188,12 @@ func main() {
 			CategoryID:    categories[2].ID, // City Bike
 			Specifications: models.Specifications{
 				FrameMaterial: "Steel",
-				WheelSize:     "26 inches",
+				WheelSize:     models.Measurement{Value: 26, Unit: models.UnitInch},
 				GearCount:     7,
 				BrakeType:     "V-Brake",
 				Suspension:    "None",
-				Weight:        "15 kg",
-				MaxLoad:       "110 kg",
+				Weight:        models.Measurement{Value: 15, Unit: models.UnitKilogram},
+				MaxLoad:       models.Measurement{Value: 110, Unit: models.UnitKilogram},
 			},
 			CustomizationOptions: []models.CustomizationOption{
 				{Name: "frame_color", Options: []string{"Mint", "Cream", "Black", "Pink"}},
@@ -165,17 +202,8 @@ func main() {
 			},
 			Description: "Comfortable city bike perfect for daily commuting",
 			ImageURL:    "/images/city-comfort-26.jpg",
-			Reviews: []models.Review{
-				{
-					ReviewID:     primitive.NewObjectID(),
-					CustomerName: "Dana Sultanova",
-					Rating:       4,
-					Comment:      "Great quality, fast delivery. Perfect for my daily commute.",
-					ReviewDate:   time.Now().AddDate(0, 0, -15),
-				},
-			},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
 		},
 		{
 			ID:            primitive.NewObjectID(),
@@ -186,12 +214,16 @@ func main() {
 			CategoryID:    categories[3].ID, // BMX
 			Specifications: models.Specifications{
 				FrameMaterial: "Chromoly Steel",
-				WheelSize:     "20 inches",
+				WheelSize:     models.Measurement{Value: 20, Unit: models.UnitInch},
 				GearCount:     1,
 				BrakeType:     "U-Brake",
 				Suspension:    "None",
-				Weight:        "11 kg",
-				MaxLoad:       "90 kg",
+				Weight:        models.Measurement{Value: 11, Unit: models.UnitKilogram},
+				MaxLoad:       models.Measurement{Value: 90, Unit: models.UnitKilogram},
+			},
+			Attributes: []models.AttributeValue{
+				{Name: "discipline", Value: "Freestyle"},
+				{Name: "rotor_system", Value: true},
 			},
 			CustomizationOptions: []models.CustomizationOption{
 				{Name: "frame_color", Options: []string{"Matte Black", "Chrome", "Neon Green", "Orange"}},
@@ -200,7 +232,6 @@ func main() {
 			},
 			Description: "Professional BMX bike for freestyle tricks and skatepark riding",
 			ImageURL:    "/images/freestyle-x20.jpg",
-			Reviews:     []models.Review{},
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
@@ -213,12 +244,18 @@ func main() {
 			CategoryID:    categories[4].ID, // Electric Bike
 			Specifications: models.Specifications{
 				FrameMaterial: "Aluminum",
-				WheelSize:     "27.5 inches",
+				WheelSize:     models.Measurement{Value: 27.5, Unit: models.UnitInch},
 				GearCount:     9,
 				BrakeType:     "Hydraulic Disc",
 				Suspension:    "Front Suspension",
-				Weight:        "22 kg",
-				MaxLoad:       "130 kg",
+				Weight:        models.Measurement{Value: 22, Unit: models.UnitKilogram},
+				MaxLoad:       models.Measurement{Value: 130, Unit: models.UnitKilogram},
+			},
+			Attributes: []models.AttributeValue{
+				{Name: "battery_capacity", Value: 400.0, Unit: "Wh"},
+				{Name: "motor_power", Value: int64(500), Unit: "W"},
+				{Name: "range", Value: 80.0, Unit: "km"},
+				{Name: "motor_position", Value: "Hub"},
 			},
 			CustomizationOptions: []models.CustomizationOption{
 				{Name: "frame_color", Options: []string{"Grey", "Black", "White"}},
@@ -227,17 +264,8 @@ func main() {
 			},
 			Description: "Electric bike with 500W motor and 80km range",
 			ImageURL:    "/images/ecorider-e500.jpg",
-			Reviews: []models.Review{
-				{
-					ReviewID:     primitive.NewObjectID(),
-					CustomerName: "Marat Kozhaev",
-					Rating:       5,
-					Comment:      "Amazing e-bike! The battery lasts forever and the motor is powerful.",
-					ReviewDate:   time.Now().AddDate(0, 0, -7),
-				},
-			},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
 		},
 		{
 			ID:            primitive.NewObjectID(),
@@ -245,15 +273,15 @@ func main() {
 			Brand:         "RockRider",
 			Price:         380000,
 			StockQuantity: 18,
-			CategoryID:    categories[0].ID, // Mountain Bike
+			CategoryID:    categories[5].ID, // Mountain Bike > Trail
 			Specifications: models.Specifications{
 				FrameMaterial: "Aluminum",
-				WheelSize:     "27.5 inches",
+				WheelSize:     models.Measurement{Value: 27.5, Unit: models.UnitInch},
 				GearCount:     18,
 				BrakeType:     "Mechanical Disc",
 				Suspension:    "Hardtail",
-				Weight:        "14 kg",
-				MaxLoad:       "115 kg",
+				Weight:        models.Measurement{Value: 14, Unit: models.UnitKilogram},
+				MaxLoad:       models.Measurement{Value: 115, Unit: models.UnitKilogram},
 			},
 			CustomizationOptions: []models.CustomizationOption{
 				{Name: "frame_color", Options: []string{"Orange", "Blue", "Black"}},
@@ -262,7 +290,6 @@ func main() {
 			},
 			Description: "Versatile mountain bike for beginners and intermediate riders",
 			ImageURL:    "/images/summit-explorer-275.jpg",
-			Reviews:     []models.Review{},
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
@@ -275,12 +302,12 @@ func main() {
 			CategoryID:    categories[1].ID, // Road Bike
 			Specifications: models.Specifications{
 				FrameMaterial: "Carbon Fiber",
-				WheelSize:     "700C",
+				WheelSize:     models.Measurement{Value: 28, Unit: models.UnitInch, Label: "700C"},
 				GearCount:     24,
 				BrakeType:     "Disc Brakes",
 				Suspension:    "None",
-				Weight:        "7.5 kg",
-				MaxLoad:       "95 kg",
+				Weight:        models.Measurement{Value: 7.5, Unit: models.UnitKilogram},
+				MaxLoad:       models.Measurement{Value: 95, Unit: models.UnitKilogram},
 			},
 			CustomizationOptions: []models.CustomizationOption{
 				{Name: "frame_color", Options: []string{"Stealth Black", "Team Red", "Sky Blue"}},
@@ -289,7 +316,6 @@ func main() {
 			},
 			Description: "Top-tier aerodynamic road bike for professional cyclists",
 			ImageURL:    "/images/aero-elite-700.jpg",
-			Reviews:     []models.Review{},
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
@@ -302,12 +328,12 @@ func main() {
 			CategoryID:    categories[2].ID, // City Bike
 			Specifications: models.Specifications{
 				FrameMaterial: "Aluminum",
-				WheelSize:     "28 inches",
+				WheelSize:     models.Measurement{Value: 28, Unit: models.UnitInch},
 				GearCount:     8,
 				BrakeType:     "Roller Brakes",
 				Suspension:    "Seatpost Suspension",
-				Weight:        "14 kg",
-				MaxLoad:       "120 kg",
+				Weight:        models.Measurement{Value: 14, Unit: models.UnitKilogram},
+				MaxLoad:       models.Measurement{Value: 120, Unit: models.UnitKilogram},
 			},
 			CustomizationOptions: []models.CustomizationOption{
 				{Name: "frame_color", Options: []string{"Navy", "Silver", "Burgundy"}},
@@ -316,19 +342,65 @@ func main() {
 			},
 			Description: "Premium city bike with integrated lights and fenders",
 			ImageURL:    "/images/urban-glide-28.jpg",
-			Reviews:     []models.Review{},
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
 	}
 
+	// Seed Reviews
+	reviews := []models.Review{
+		{
+			ReviewID:     primitive.NewObjectID(),
+			BicycleID:    bicycles[0].ID,
+			CustomerName: "Aidos Bekzhanov",
+			Rating:       5,
+			Comment:      "Excellent bike for mountain trails! Great suspension.",
+			ReviewDate:   time.Now().AddDate(0, -1, 0),
+			Status:       models.ReviewStatusApproved,
+		},
+		{
+			ReviewID:     primitive.NewObjectID(),
+			BicycleID:    bicycles[2].ID,
+			CustomerName: "Dana Sultanova",
+			Rating:       4,
+			Comment:      "Great quality, fast delivery. Perfect for my daily commute.",
+			ReviewDate:   time.Now().AddDate(0, 0, -15),
+			Status:       models.ReviewStatusApproved,
+		},
+		{
+			ReviewID:     primitive.NewObjectID(),
+			BicycleID:    bicycles[4].ID,
+			CustomerName: "Marat Kozhaev",
+			Rating:       5,
+			Comment:      "Amazing e-bike! The battery lasts forever and the motor is powerful.",
+			ReviewDate:   time.Now().AddDate(0, 0, -7),
+			Status:       models.ReviewStatusApproved,
+		},
+	}
+
 	bicycleDocs := make([]interface{}, len(bicycles))
 	for i, bike := range bicycles {
+		var bikeReviews []models.Review
+		for _, review := range reviews {
+			if review.BicycleID == bike.ID {
+				bikeReviews = append(bikeReviews, review)
+			}
+		}
+
+		bike.Status = models.BicycleStatusActive
+		bike.SummarizeReviews(bikeReviews)
 		bicycleDocs[i] = bike
 	}
 	database.GetCollection("bicycles").InsertMany(ctx, bicycleDocs)
 	log.Printf("Inserted %d bicycles", len(bicycles))
 
+	reviewDocs := make([]interface{}, len(reviews))
+	for i, review := range reviews {
+		reviewDocs[i] = review
+	}
+	database.GetCollection("reviews").InsertMany(ctx, reviewDocs)
+	log.Printf("Inserted %d reviews", len(reviews))
+
 	// Seed Customers
 	adminPassword, _ := utils.HashPassword("admin123")
 	customerPassword, _ := utils.HashPassword("password123")
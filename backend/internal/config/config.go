@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +16,33 @@ type Config struct {
 	Port           string
 	GinMode        string
 	AllowedOrigins string
+	// Comma-separated proxy addresses or CIDRs whose X-Forwarded-For is trusted; with none,
+	// the client IP is always the connection's remote address
+	TrustedProxies string
+	RateLimitStore string
+
+	// OpenID Connect login, enabled when OIDCIssuerURL is set
+	OIDCIssuerURL         string
+	OIDCClientID          string
+	OIDCClientSecret      string
+	OIDCRedirectURL       string
+	OIDCPostLoginRedirect string
+
+	// Archived bicycles without orders are purged after ArchiveRetention
+	ArchiveRetention time.Duration
+
+	// Uploaded product images are stored in UploadDir and served from UploadBaseURL
+	UploadDir     string
+	UploadBaseURL string
+	MaxImageBytes int64
+
+	// Only customers with a delivered order of a bicycle may review it
+	RequireVerifiedPurchase bool
+	// Approved reviews are hidden for moderation once they have this many reports
+	ReviewReportThreshold int
+
+	// Catalog import files may be at most MaxImportBytes
+	MaxImportBytes int64
 }
 
 var AppConfig *Config
@@ -31,6 +60,25 @@ func LoadConfig() *Config {
 		Port:           getEnv("PORT", "8080"),
 		GinMode:        getEnv("GIN_MODE", "debug"),
 		AllowedOrigins: getEnv("ALLOWED_ORIGINS", "http://localhost:3000"),
+		TrustedProxies: getEnv("TRUSTED_PROXIES", ""),
+		RateLimitStore: getEnv("RATE_LIMIT_STORE", "memory"),
+
+		OIDCIssuerURL:         getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:          getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:      getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:       getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/v1/auth/oidc/callback"),
+		OIDCPostLoginRedirect: getEnv("OIDC_POST_LOGIN_REDIRECT", ""),
+
+		ArchiveRetention: time.Duration(getEnvInt("ARCHIVE_RETENTION_DAYS", 90)) * 24 * time.Hour,
+
+		UploadDir:     getEnv("UPLOAD_DIR", "uploads"),
+		UploadBaseURL: getEnv("UPLOAD_BASE_URL", "/uploads"),
+		MaxImageBytes: int64(getEnvInt("MAX_IMAGE_UPLOAD_MB", 10)) << 20,
+
+		RequireVerifiedPurchase: getEnv("REQUIRE_VERIFIED_PURCHASE", "false") == "true",
+		ReviewReportThreshold:   getEnvInt("REVIEW_REPORT_THRESHOLD", 3),
+
+		MaxImportBytes: int64(getEnvInt("MAX_IMPORT_UPLOAD_MB", 20)) << 20,
 	}
 
 	return AppConfig
@@ -42,3 +90,16 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: %s is not a number, using %d", key, defaultValue)
+		return defaultValue
+	}
+	return number
+}
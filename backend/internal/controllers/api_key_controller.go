@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyController() *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: services.NewAPIKeyService(),
+	}
+}
+
+// GetAll godoc
+// @Summary Get all API keys
+// @Description Get a list of all API keys, including revoked ones (Admin only)
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.APIKey}
+// @Router /admin/api-keys [get]
+func (c *APIKeyController) GetAll(ctx *gin.Context) {
+	keys, err := c.apiKeyService.GetKeys(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch API keys",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    keys,
+	})
+}
+
+// Create godoc
+// @Summary Create an API key
+// @Description Create an API key for a machine-to-machine integration (Admin only). The key is only shown in this response.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body models.APIKeyInput true "API key data"
+// @Success 201 {object} models.APIResponse{data=models.APIKeyCreatedResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/api-keys [post]
+func (c *APIKeyController) Create(ctx *gin.Context) {
+	adminID, _ := ctx.Get("userID")
+
+	var input models.APIKeyInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	key, err := c.apiKeyService.CreateKey(ctx.Request.Context(), adminID.(string), input)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "API key created successfully. Store it now, it will not be shown again",
+		Data:    key,
+	})
+}
+
+// Revoke godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key so it can no longer be used (Admin only)
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/api-keys/{id} [delete]
+func (c *APIKeyController) Revoke(ctx *gin.Context) {
+	if err := c.apiKeyService.RevokeKey(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "API key revoked successfully",
+	})
+}
@@ -1,20 +1,26 @@
 package controllers
 
 import (
+	"bicycle-store/internal/config"
 	"bicycle-store/internal/models"
 	"bicycle-store/internal/services"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthController struct {
 	authService *services.AuthService
+	oidcService *services.OIDCService
 }
 
 func NewAuthController() *AuthController {
 	return &AuthController{
 		authService: services.NewAuthService(),
+		oidcService: services.NewOIDCService(),
 	}
 }
 
@@ -108,3 +114,97 @@ func (c *AuthController) GetMe(ctx *gin.Context) {
 		Data:    user,
 	})
 }
+
+// OIDCLogin godoc
+// @Summary Login with the company identity provider
+// @Description Redirect to the OpenID Connect provider to start an authorization code + PKCE login
+// @Tags auth
+// @Success 302
+// @Failure 503 {object} models.APIResponse
+// @Router /auth/oidc/login [get]
+func (c *AuthController) OIDCLogin(ctx *gin.Context) {
+	authURL, state, err := c.oidcService.BeginLogin(ctx.Request.Context())
+	if err != nil {
+		status := http.StatusBadGateway
+		if err == services.ErrOIDCNotConfigured {
+			status = http.StatusServiceUnavailable
+		}
+		ctx.JSON(status, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Bind the login to this browser, so that a callback started elsewhere can't sign it in
+	setOIDCStateCookie(ctx, state, int(services.OIDCLoginTimeout.Seconds()))
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback godoc
+// @Summary OpenID Connect callback
+// @Description Complete an identity provider login started by this browser and return the store's JWT. Redirects to OIDC_POST_LOGIN_REDIRECT with the token in the URL fragment when configured.
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "Login state"
+// @Success 200 {object} models.AuthResponse
+// @Failure 401 {object} models.APIResponse
+// @Router /auth/oidc/callback [get]
+func (c *AuthController) OIDCCallback(ctx *gin.Context) {
+	if providerError := ctx.Query("error"); providerError != "" {
+		ctx.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Identity provider returned an error: " + providerError,
+		})
+		return
+	}
+
+	state := ctx.Query("state")
+	cookie, err := ctx.Cookie(oidcStateCookie)
+	if err != nil || state == "" || cookie != state {
+		ctx.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Login was not started in this browser",
+		})
+		return
+	}
+	setOIDCStateCookie(ctx, "", -1)
+
+	response, err := c.oidcService.CompleteLogin(ctx.Request.Context(), ctx.Query("code"), state)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if err == services.ErrOIDCNotConfigured {
+			status = http.StatusServiceUnavailable
+		}
+		ctx.JSON(status, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Hand the token to the frontend in the fragment so it never reaches server logs
+	if redirect := config.AppConfig.OIDCPostLoginRedirect; redirect != "" {
+		ctx.Redirect(http.StatusFound, redirect+"#token="+url.QueryEscape(response.Token))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+const oidcStateCookie = "oidc_state"
+
+// setOIDCStateCookie sets the login state cookie for the OIDC routes, or deletes it
+// when maxAge is negative
+func setOIDCStateCookie(ctx *gin.Context, state string, maxAge int) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     path.Dir(ctx.Request.URL.Path),
+		MaxAge:   maxAge,
+		Secure:   strings.HasPrefix(config.AppConfig.OIDCRedirectURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
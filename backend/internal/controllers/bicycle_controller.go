@@ -1,24 +1,35 @@
 package controllers
 
 import (
+	"bicycle-store/internal/config"
 	"bicycle-store/internal/models"
 	"bicycle-store/internal/repositories"
 	"bicycle-store/internal/services"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type BicycleController struct {
-	repo         *repositories.BicycleRepository
-	orderService *services.OrderService
+	repo            *repositories.BicycleRepository
+	bicycleService  *services.BicycleService
+	searchService   *services.SearchService
+	categoryService *services.CategoryService
 }
 
 func NewBicycleController() *BicycleController {
 	return &BicycleController{
-		repo:         repositories.NewBicycleRepository(),
-		orderService: services.NewOrderService(),
+		repo:            repositories.NewBicycleRepository(),
+		bicycleService:  services.NewBicycleService(),
+		searchService:   services.NewSearchService(),
+		categoryService: services.NewCategoryService(),
 	}
 }
 
@@ -29,16 +40,33 @@ func NewBicycleController() *BicycleController {
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
-// @Param category_id query string false "Filter by category ID"
+// @Param category_id query string false "Filter by category ID or slug, including subcategories"
 // @Param min_price query number false "Minimum price"
 // @Param max_price query number false "Maximum price"
 // @Param brand query string false "Filter by brand"
-// @Param search query string false "Search in model name, brand, description"
-// @Param sort query string false "Sort field" default(created_at)
+// @Param search query string false "Full-text search in model name, brand, description"
+// @Param min_weight query number false "Minimum weight in kg"
+// @Param max_weight query number false "Maximum weight in kg"
+// @Param min_gears query int false "Minimum gear count"
+// @Param max_gears query int false "Maximum gear count"
+// @Param min_load query number false "Minimum max load in kg"
+// @Param max_load query number false "Maximum max load in kg"
+// @Param brake_type query string false "Filter by brake type"
+// @Param suspension query string false "Filter by suspension"
+// @Param frame_material query string false "Filter by frame material"
+// @Param attr[name] query string false "Filter by category attribute value, e.g. attr[motor_power]=250"
+// @Param attr_min[name] query number false "Minimum value of a numeric category attribute"
+// @Param attr_max[name] query number false "Maximum value of a numeric category attribute"
+// @Param sort query string false "Sort field, weight, max_load, gear_count, or relevance to rank search matches" default(created_at)
 // @Param order query string false "Sort order (asc/desc)" default(desc)
 // @Success 200 {object} models.PaginatedResponse{data=[]models.Bicycle}
 // @Router /bicycles [get]
 func (c *BicycleController) GetAll(ctx *gin.Context) {
+	c.list(ctx, false)
+}
+
+// list responds with a page of bicycles matching the query filters
+func (c *BicycleController) list(ctx *gin.Context, includeHidden bool) {
 	var filter models.BicycleFilter
 	if err := ctx.ShouldBindQuery(&filter); err != nil {
 		ctx.JSON(http.StatusBadRequest, models.APIResponse{
@@ -47,6 +75,26 @@ func (c *BicycleController) GetAll(ctx *gin.Context) {
 		})
 		return
 	}
+	if err := bindAttributeFilters(ctx, &filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	filter.IncludeHidden = includeHidden
+	if filter.CategoryID != "" {
+		// A category includes its subcategories
+		categoryIDs, err := c.categoryService.SubtreeIDs(ctx.Request.Context(), filter.CategoryID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Error:   "Failed to resolve category",
+			})
+			return
+		}
+		filter.CategoryIDs = categoryIDs
+	}
 
 	// Set defaults
 	if filter.Page < 1 {
@@ -71,15 +119,141 @@ func (c *BicycleController) GetAll(ctx *gin.Context) {
 		return
 	}
 
+	// Shoppers see running sales; admins see prices as stored
+	if !includeHidden {
+		now := time.Now()
+		for i := range bicycles {
+			bicycles[i].ApplySale(now)
+		}
+	}
+
 	totalPages := (total + int64(filter.Limit) - 1) / int64(filter.Limit)
 
+	// Offer corrected queries when a search finds nothing
+	var suggestions []string
+	if total == 0 && filter.Search != "" {
+		suggestions, err = c.searchService.Suggest(ctx.Request.Context(), filter.Search)
+		if err != nil {
+			log.Printf("Warning: Failed to build search suggestions: %v", err)
+		}
+	}
+
 	ctx.JSON(http.StatusOK, models.PaginatedResponse{
-		Success:    true,
-		Data:       bicycles,
-		Page:       filter.Page,
-		Limit:      filter.Limit,
-		Total:      total,
-		TotalPages: totalPages,
+		Success:     true,
+		Data:        bicycles,
+		Page:        filter.Page,
+		Limit:       filter.Limit,
+		Total:       total,
+		TotalPages:  totalPages,
+		Suggestions: suggestions,
+	})
+}
+
+// bindAttributeFilters reads the attr, attr_min and attr_max query maps
+func bindAttributeFilters(ctx *gin.Context, filter *models.BicycleFilter) error {
+	filter.Attributes = ctx.QueryMap("attr")
+	filter.AttributeMin = make(map[string]float64)
+	filter.AttributeMax = make(map[string]float64)
+
+	bounds := map[string]map[string]float64{"attr_min": filter.AttributeMin, "attr_max": filter.AttributeMax}
+	for param, values := range bounds {
+		for name, text := range ctx.QueryMap(param) {
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return errors.New(param + "[" + name + "] must be a number")
+			}
+			values[name] = value
+		}
+	}
+	return nil
+}
+
+// Suggest godoc
+// @Summary Autocomplete catalog search
+// @Description Get model names, brands and categories with a word starting with the query, with the type of each match
+// @Tags bicycles
+// @Produce json
+// @Param q query string true "Search prefix"
+// @Param limit query int false "Maximum suggestions (max 20)" default(8)
+// @Success 200 {object} models.APIResponse{data=[]models.SearchSuggestion}
+// @Router /bicycles/suggest [get]
+func (c *BicycleController) Suggest(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "8"))
+	if err != nil || limit < 1 || limit > 20 {
+		limit = 8
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    c.searchService.Autocomplete(ctx.Query("q"), limit),
+	})
+}
+
+// GetFacets godoc
+// @Summary Get catalog filter counts
+// @Description Get bicycle counts per brand, category, specification value, price range and rating. Each facet respects every active filter except its own.
+// @Tags bicycles
+// @Produce json
+// @Param category_id query string false "Filter by category ID or slug, including subcategories"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param brand query string false "Filter by brand"
+// @Param search query string false "Full-text search in model name, brand, description"
+// @Param min_weight query number false "Minimum weight in kg"
+// @Param max_weight query number false "Maximum weight in kg"
+// @Param min_gears query int false "Minimum gear count"
+// @Param max_gears query int false "Maximum gear count"
+// @Param min_load query number false "Minimum max load in kg"
+// @Param max_load query number false "Maximum max load in kg"
+// @Param brake_type query string false "Filter by brake type"
+// @Param suspension query string false "Filter by suspension"
+// @Param frame_material query string false "Filter by frame material"
+// @Param attr[name] query string false "Filter by category attribute value, e.g. attr[motor_power]=250"
+// @Param attr_min[name] query number false "Minimum value of a numeric category attribute"
+// @Param attr_max[name] query number false "Maximum value of a numeric category attribute"
+// @Success 200 {object} models.APIResponse{data=models.BicycleFacets}
+// @Router /bicycles/facets [get]
+func (c *BicycleController) GetFacets(ctx *gin.Context) {
+	var filter models.BicycleFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := bindAttributeFilters(ctx, &filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if filter.CategoryID != "" {
+		// A category includes its subcategories
+		categoryIDs, err := c.categoryService.SubtreeIDs(ctx.Request.Context(), filter.CategoryID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Error:   "Failed to resolve category",
+			})
+			return
+		}
+		filter.CategoryIDs = categoryIDs
+	}
+
+	facets, err := c.repo.GetFacets(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch facets",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    facets,
 	})
 }
 
@@ -102,7 +276,7 @@ func (c *BicycleController) GetByID(ctx *gin.Context) {
 		return
 	}
 
-	bicycle, err := c.repo.GetByID(ctx.Request.Context(), id)
+	bicycle, err := c.bicycleService.GetBicycle(ctx.Request.Context(), id, false)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, models.APIResponse{
 			Success: false,
@@ -117,6 +291,78 @@ func (c *BicycleController) GetByID(ctx *gin.Context) {
 	})
 }
 
+// GetVariants godoc
+// @Summary Get bicycle variant availability
+// @Description Get every option combination of a bicycle with its SKU, price and stock status
+// @Tags bicycles
+// @Produce json
+// @Param id path string true "Bicycle ID"
+// @Success 200 {object} models.APIResponse{data=[]models.VariantAvailability}
+// @Failure 404 {object} models.APIResponse
+// @Router /bicycles/{id}/variants [get]
+func (c *BicycleController) GetVariants(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return
+	}
+
+	bicycle, err := c.bicycleService.GetBicycle(ctx.Request.Context(), id, false)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Error:   "Bicycle not found",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    bicycle.Availability(),
+	})
+}
+
+// Compare godoc
+// @Summary Compare bicycles side by side
+// @Description Compare 2 to 4 bicycles field by field: price, rating, price per kg, specifications and category attributes, with the fields that differ marked
+// @Tags bicycles
+// @Produce json
+// @Param ids query string true "Comma-separated bicycle IDs"
+// @Success 200 {object} models.APIResponse{data=models.Comparison}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /bicycles/compare [get]
+func (c *BicycleController) Compare(ctx *gin.Context) {
+	ids := strings.Split(ctx.Query("ids"), ",")
+
+	comparison, err := c.bicycleService.CompareBicycles(ctx.Request.Context(), ids)
+	if err != nil {
+		var notFound *services.BicyclesNotFoundError
+		switch {
+		case errors.As(err, &notFound):
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+				Data:    notFound,
+			})
+		default:
+			ctx.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    comparison,
+	})
+}
+
 // Create godoc
 // @Summary Create a new bicycle
 // @Description Create a new bicycle (Admin only)
@@ -129,6 +375,8 @@ func (c *BicycleController) GetByID(ctx *gin.Context) {
 // @Failure 400 {object} models.APIResponse
 // @Router /bicycles [post]
 func (c *BicycleController) Create(ctx *gin.Context) {
+	adminID, _ := ctx.Get("userID")
+
 	var input models.BicycleInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, models.APIResponse{
@@ -138,9 +386,9 @@ func (c *BicycleController) Create(ctx *gin.Context) {
 		return
 	}
 
-	bicycle, err := c.repo.Create(ctx.Request.Context(), input)
+	bicycle, err := c.bicycleService.CreateBicycle(ctx.Request.Context(), input, adminID.(string))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Error:   "Failed to create bicycle: " + err.Error(),
 		})
@@ -168,6 +416,8 @@ func (c *BicycleController) Create(ctx *gin.Context) {
 // @Failure 404 {object} models.APIResponse
 // @Router /bicycles/{id} [put]
 func (c *BicycleController) Update(ctx *gin.Context) {
+	adminID, _ := ctx.Get("userID")
+
 	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, models.APIResponse{
@@ -186,11 +436,18 @@ func (c *BicycleController) Update(ctx *gin.Context) {
 		return
 	}
 
-	bicycle, err := c.repo.Update(ctx.Request.Context(), id, input)
+	bicycle, err := c.bicycleService.UpdateBicycle(ctx.Request.Context(), id, input, adminID.(string))
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, models.APIResponse{
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Bicycle not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Error:   "Bicycle not found",
+			Error:   err.Error(),
 		})
 		return
 	}
@@ -204,13 +461,15 @@ func (c *BicycleController) Update(ctx *gin.Context) {
 
 // Delete godoc
 // @Summary Delete a bicycle
-// @Description Delete a bicycle (Admin only)
+// @Description Archive a bicycle so it is hidden from the catalog but kept for orders and reports (Admin only)
 // @Tags bicycles
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Bicycle ID"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse{data=services.BicycleInUseError}
 // @Router /bicycles/{id} [delete]
 func (c *BicycleController) Delete(ctx *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
@@ -222,11 +481,26 @@ func (c *BicycleController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.repo.Delete(ctx.Request.Context(), id); err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to delete bicycle",
-		})
+	if err := c.bicycleService.DeleteBicycle(ctx.Request.Context(), id); err != nil {
+		var inUse *services.BicycleInUseError
+		switch {
+		case errors.As(err, &inUse):
+			ctx.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+				Data:    inUse,
+			})
+		case err == mongo.ErrNoDocuments:
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Bicycle not found",
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Error:   "Failed to delete bicycle",
+			})
+		}
 		return
 	}
 
@@ -236,47 +510,169 @@ func (c *BicycleController) Delete(ctx *gin.Context) {
 	})
 }
 
-// AddReview godoc
-// @Summary Add a review to a bicycle
-// @Description Add a customer review to a bicycle
-// @Tags bicycles
-// @Accept json
+// AdminGetAll godoc
+// @Summary Get all bicycles including drafts and archived
+// @Description Get a paginated list of bicycles in any status, with the same filters as GET /bicycles (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Only bicycles with this status (draft, active, archived)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param search query string false "Full-text search in model name, brand, description"
+// @Param sort query string false "Sort field" default(created_at)
+// @Param order query string false "Sort order (asc/desc)" default(desc)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.Bicycle}
+// @Router /admin/bicycles [get]
+func (c *BicycleController) AdminGetAll(ctx *gin.Context) {
+	c.list(ctx, true)
+}
+
+// AdminGetByID godoc
+// @Summary Get bicycle by ID in any status
+// @Description Get a single bicycle, including drafts and archived bicycles (Admin only)
+// @Tags admin
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Bicycle ID"
-// @Param input body models.ReviewInput true "Review data"
-// @Success 201 {object} models.APIResponse{data=models.Bicycle}
-// @Failure 400 {object} models.APIResponse
-// @Router /bicycles/{id}/reviews [post]
-func (c *BicycleController) AddReview(ctx *gin.Context) {
-	bicycleID := ctx.Param("id")
-	customerID, _ := ctx.Get("userID")
+// @Success 200 {object} models.APIResponse{data=models.Bicycle}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/bicycles/{id} [get]
+func (c *BicycleController) AdminGetByID(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return
+	}
 
-	var input models.ReviewInput
-	if err := ctx.ShouldBindJSON(&input); err != nil {
+	bicycle, err := c.bicycleService.GetBicycle(ctx.Request.Context(), id, true)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Error:   "Bicycle not found",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    bicycle,
+	})
+}
+
+// GetPriceHistory godoc
+// @Summary Get bicycle price history
+// @Description Get the regular price changes of a bicycle with who made them, newest first, and its sale prices (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Success 200 {object} models.APIResponse{data=models.PriceHistory}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/bicycles/{id}/price-history [get]
+func (c *BicycleController) GetPriceHistory(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Error:   err.Error(),
+			Error:   "Invalid bicycle ID",
 		})
 		return
 	}
 
-	bicycle, err := c.orderService.AddReview(ctx.Request.Context(), customerID.(string), bicycleID, input)
+	history, err := c.bicycleService.GetPriceHistory(ctx.Request.Context(), id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Bicycle not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch price history",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    history,
+	})
+}
+
+// Restore godoc
+// @Summary Restore an archived bicycle
+// @Description Make an archived bicycle active again (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Success 200 {object} models.APIResponse{data=models.Bicycle}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/bicycles/{id}/restore [post]
+func (c *BicycleController) Restore(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Error:   err.Error(),
+			Error:   "Invalid bicycle ID",
 		})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, models.APIResponse{
+	bicycle, err := c.bicycleService.RestoreBicycle(ctx.Request.Context(), id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Archived bicycle not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to restore bicycle",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "Review added successfully",
+		Message: "Bicycle restored successfully",
 		Data:    bicycle,
 	})
 }
 
+// Purge godoc
+// @Summary Purge old archived bicycles
+// @Description Permanently remove bicycles archived longer than the retention period that no order refers to. Also runs daily in the background. (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse
+// @Router /admin/bicycles/purge [post]
+func (c *BicycleController) Purge(ctx *gin.Context) {
+	purged, err := c.bicycleService.PurgeArchived(ctx.Request.Context(), config.AppConfig.ArchiveRetention)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to purge bicycles",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Archived bicycles purged",
+		Data:    gin.H{"purged": purged},
+	})
+}
+
 // UpdateStock godoc
 // @Summary Update bicycle stock
 // @Description Update the stock quantity of a bicycle (Admin only)
@@ -284,8 +680,9 @@ func (c *BicycleController) AddReview(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path string true "Bicycle ID"
-// @Param input body object{quantity=int} true "Stock quantity change (positive or negative)"
+// @Param input body object{quantity=int,sku=string} true "Stock quantity change (positive or negative), and the variant SKU for bicycles with variants"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.APIResponse
 // @Router /bicycles/{id}/stock [patch]
@@ -300,7 +697,8 @@ func (c *BicycleController) UpdateStock(ctx *gin.Context) {
 	}
 
 	var input struct {
-		Quantity int `json:"quantity" binding:"required"`
+		Quantity int    `json:"quantity" binding:"required"`
+		SKU      string `json:"sku"`
 	}
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, models.APIResponse{
@@ -310,10 +708,17 @@ func (c *BicycleController) UpdateStock(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.repo.UpdateStock(ctx.Request.Context(), id, input.Quantity); err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+	if err := c.bicycleService.UpdateStock(ctx.Request.Context(), id, input.SKU, input.Quantity); err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Bicycle or variant not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Error:   "Failed to update stock",
+			Error:   err.Error(),
 		})
 		return
 	}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/services"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CatalogController struct {
+	catalogService *services.CatalogService
+}
+
+func NewCatalogController() *CatalogController {
+	return &CatalogController{
+		catalogService: services.NewCatalogService(),
+	}
+}
+
+// Import godoc
+// @Summary Import bicycles
+// @Description Import a CSV or NDJSON catalog file in the background. Each row updates the bicycle with its ID, or else with its SKU, or else with its model name and brand, and creates one when none matches; a dry run only validates the rows. Poll the returned job for progress and row errors (Admin only)
+// @Tags admin
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "Catalog file"
+// @Param format formData string false "csv or ndjson; taken from the file extension by default"
+// @Param dry_run formData bool false "Validate without writing"
+// @Success 202 {object} models.APIResponse{data=models.ImportJob}
+// @Failure 400 {object} models.APIResponse
+// @Failure 413 {object} models.APIResponse
+// @Router /admin/bicycles/import [post]
+func (c *CatalogController) Import(ctx *gin.Context) {
+	adminID, _ := ctx.Get("userID")
+
+	// Leave room for the other multipart fields before cutting the body off
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.catalogService.MaxImportBytes()+1<<20)
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, models.APIResponse{
+				Success: false,
+				Error:   services.ErrImportTooLarge.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "A catalog file is required",
+		})
+		return
+	}
+
+	format, err := services.CatalogFormat(ctx.PostForm("format"), file.Filename)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Failed to read catalog file",
+		})
+		return
+	}
+	defer content.Close()
+
+	dryRun := ctx.PostForm("dry_run") == "true"
+	job, err := c.catalogService.StartImport(ctx.Request.Context(), adminID.(string), file.Filename, format, dryRun, content)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == services.ErrImportTooLarge {
+			status = http.StatusRequestEntityTooLarge
+		}
+		ctx.JSON(status, models.APIResponse{
+			Success: false,
+			Error:   "Failed to import catalog: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, models.APIResponse{
+		Success: true,
+		Message: "Import started",
+		Data:    job,
+	})
+}
+
+// GetImportJobs godoc
+// @Summary List import jobs
+// @Description Get the 20 most recent catalog imports, without their row errors (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.ImportJob}
+// @Router /admin/import-jobs [get]
+func (c *CatalogController) GetImportJobs(ctx *gin.Context) {
+	jobs, err := c.catalogService.GetImportJobs(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch import jobs",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    jobs,
+	})
+}
+
+// GetImportJob godoc
+// @Summary Get an import job
+// @Description Get the progress of a catalog import and its row errors (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Import job ID"
+// @Success 200 {object} models.APIResponse{data=models.ImportJob}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/import-jobs/{id} [get]
+func (c *CatalogController) GetImportJob(ctx *gin.Context) {
+	job, err := c.catalogService.GetImportJob(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Import job not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch import job",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    job,
+	})
+}
+
+// Export godoc
+// @Summary Export bicycles
+// @Description Download every bicycle that isn't archived as CSV or NDJSON, in the format the import reads: a row per bicycle with its ID, customization options and variants (Admin only)
+// @Tags admin
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param format query string false "csv (default) or ndjson"
+// @Success 200 {file} file
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/bicycles/export [get]
+func (c *CatalogController) Export(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", models.CatalogFormatCSV)
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case models.CatalogFormatCSV:
+	case models.CatalogFormatNDJSON:
+		contentType = "application/x-ndjson"
+	default:
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   services.ErrCatalogFormat.Error(),
+		})
+		return
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", "attachment; filename=bicycles."+format)
+	ctx.Status(http.StatusOK)
+
+	// Rows are streamed as they are read, so a failure can only cut the file short
+	if err := c.catalogService.ExportCatalog(ctx.Request.Context(), format, ctx.Writer); err != nil {
+		log.Printf("Warning: Catalog export failed: %v", err)
+	}
+}
@@ -3,19 +3,24 @@ package controllers
 import (
 	"bicycle-store/internal/models"
 	"bicycle-store/internal/repositories"
+	"bicycle-store/internal/services"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type CategoryController struct {
-	repo *repositories.CategoryRepository
+	repo            *repositories.CategoryRepository
+	categoryService *services.CategoryService
 }
 
 func NewCategoryController() *CategoryController {
 	return &CategoryController{
-		repo: repositories.NewCategoryRepository(),
+		repo:            repositories.NewCategoryRepository(),
+		categoryService: services.NewCategoryService(),
 	}
 }
 
@@ -42,26 +47,72 @@ func (c *CategoryController) GetAll(ctx *gin.Context) {
 	})
 }
 
-// GetByID godoc
-// @Summary Get category by ID
-// @Description Get a single category by its ID
+// GetTree godoc
+// @Summary Get the category tree
+// @Description Get the top-level categories with their subcategories nested under children
 // @Tags categories
 // @Produce json
-// @Param id path string true "Category ID"
-// @Success 200 {object} models.APIResponse{data=models.Category}
+// @Success 200 {object} models.APIResponse{data=[]models.CategoryNode}
+// @Router /categories/tree [get]
+func (c *CategoryController) GetTree(ctx *gin.Context) {
+	tree, err := c.categoryService.GetTree(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch category tree",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    tree,
+	})
+}
+
+// GetBreadcrumbs godoc
+// @Summary Get category breadcrumbs
+// @Description Get the path from the top-level category down to a category
+// @Tags categories
+// @Produce json
+// @Param id path string true "Category ID or slug"
+// @Success 200 {object} models.APIResponse{data=[]models.Breadcrumb}
 // @Failure 404 {object} models.APIResponse
-// @Router /categories/{id} [get]
-func (c *CategoryController) GetByID(ctx *gin.Context) {
-	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+// @Router /categories/{id}/breadcrumbs [get]
+func (c *CategoryController) GetBreadcrumbs(ctx *gin.Context) {
+	breadcrumbs, err := c.categoryService.GetBreadcrumbs(ctx.Request.Context(), ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Category not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Error:   "Invalid category ID",
+			Error:   "Failed to fetch breadcrumbs",
 		})
 		return
 	}
 
-	category, err := c.repo.GetByID(ctx.Request.Context(), id)
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    breadcrumbs,
+	})
+}
+
+// GetByID godoc
+// @Summary Get category by ID or slug
+// @Description Get a single category by its ID or URL slug, including the attribute schema admin forms are rendered from
+// @Tags categories
+// @Produce json
+// @Param id path string true "Category ID or slug"
+// @Success 200 {object} models.APIResponse{data=models.Category}
+// @Failure 404 {object} models.APIResponse
+// @Router /categories/{id} [get]
+func (c *CategoryController) GetByID(ctx *gin.Context) {
+	category, err := c.categoryService.GetCategory(ctx.Request.Context(), ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, models.APIResponse{
 			Success: false,
@@ -78,7 +129,7 @@ func (c *CategoryController) GetByID(ctx *gin.Context) {
 
 // Create godoc
 // @Summary Create a new category
-// @Description Create a new bicycle category (Admin only)
+// @Description Create a new bicycle category with an optional attribute schema (Admin only)
 // @Tags categories
 // @Accept json
 // @Produce json
@@ -97,11 +148,11 @@ func (c *CategoryController) Create(ctx *gin.Context) {
 		return
 	}
 
-	category, err := c.repo.Create(ctx.Request.Context(), input)
+	category, err := c.categoryService.CreateCategory(ctx.Request.Context(), input)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Error:   "Failed to create category",
+			Error:   "Failed to create category: " + err.Error(),
 		})
 		return
 	}
@@ -115,7 +166,7 @@ func (c *CategoryController) Create(ctx *gin.Context) {
 
 // Update godoc
 // @Summary Update a category
-// @Description Update an existing category (Admin only)
+// @Description Update an existing category and its attribute schema (Admin only)
 // @Tags categories
 // @Accept json
 // @Produce json
@@ -145,11 +196,18 @@ func (c *CategoryController) Update(ctx *gin.Context) {
 		return
 	}
 
-	category, err := c.repo.Update(ctx.Request.Context(), id, input)
+	category, err := c.categoryService.UpdateCategory(ctx.Request.Context(), id, input)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, models.APIResponse{
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Category not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Error:   "Category not found",
+			Error:   "Failed to update category: " + err.Error(),
 		})
 		return
 	}
@@ -163,13 +221,16 @@ func (c *CategoryController) Update(ctx *gin.Context) {
 
 // Delete godoc
 // @Summary Delete a category
-// @Description Delete a category (Admin only)
+// @Description Delete a category (Admin only). A category with subcategories or bicycles is only deleted when reassign_to names the category to move them to.
 // @Tags categories
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Category ID"
+// @Param reassign_to query string false "Category ID to move subcategories and bicycles to"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse{data=services.CategoryInUseError}
 // @Router /categories/{id} [delete]
 func (c *CategoryController) Delete(ctx *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
@@ -181,11 +242,26 @@ func (c *CategoryController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.repo.Delete(ctx.Request.Context(), id); err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to delete category",
-		})
+	if err := c.categoryService.DeleteCategory(ctx.Request.Context(), id, ctx.Query("reassign_to")); err != nil {
+		var inUse *services.CategoryInUseError
+		switch {
+		case errors.As(err, &inUse):
+			ctx.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+				Data:    inUse,
+			})
+		case err == mongo.ErrNoDocuments:
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Category not found",
+			})
+		default:
+			ctx.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   "Failed to delete category: " + err.Error(),
+			})
+		}
 		return
 	}
 
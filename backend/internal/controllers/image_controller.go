@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ImageController struct {
+	imageService *services.ImageService
+}
+
+func NewImageController() *ImageController {
+	return &ImageController{
+		imageService: services.NewImageService(),
+	}
+}
+
+// Upload godoc
+// @Summary Upload a bicycle image
+// @Description Add an image to a bicycle's gallery. JPEG, PNG and WebP are accepted; thumbnail and medium renditions are generated, each also as WebP. The first image becomes primary (Admin only)
+// @Tags bicycles
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param image formData file true "Image file"
+// @Param alt_text formData string false "Alternative text"
+// @Param primary formData bool false "Make this the primary image"
+// @Success 201 {object} models.APIResponse{data=models.Bicycle}
+// @Failure 400 {object} models.APIResponse
+// @Failure 413 {object} models.APIResponse
+// @Failure 415 {object} models.APIResponse
+// @Router /bicycles/{id}/images [post]
+func (c *ImageController) Upload(ctx *gin.Context) {
+	bicycleID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return
+	}
+
+	// Leave room for the other multipart fields before cutting the body off
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.imageService.MaxImageBytes()+1<<20)
+	file, err := ctx.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, models.APIResponse{
+				Success: false,
+				Error:   services.ErrImageTooLarge.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "An image file is required",
+		})
+		return
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Failed to read image",
+		})
+		return
+	}
+	defer content.Close()
+
+	primary := ctx.PostForm("primary") == "true"
+	bicycle, err := c.imageService.AddImage(ctx.Request.Context(), bicycleID, content, ctx.PostForm("alt_text"), primary)
+	if err != nil {
+		switch {
+		case err == mongo.ErrNoDocuments:
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Bicycle not found",
+			})
+		case err == services.ErrImageTooLarge:
+			ctx.JSON(http.StatusRequestEntityTooLarge, models.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+		case err == services.ErrUnsupportedImageType:
+			ctx.JSON(http.StatusUnsupportedMediaType, models.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   "Failed to upload image: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Image uploaded successfully",
+		Data:    bicycle,
+	})
+}
+
+// Update godoc
+// @Summary Update a bicycle image
+// @Description Change an image's alt text or make it the primary image (Admin only)
+// @Tags bicycles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param imageId path string true "Image ID"
+// @Param input body models.ImageUpdateInput true "Image changes"
+// @Success 200 {object} models.APIResponse{data=models.Bicycle}
+// @Failure 404 {object} models.APIResponse
+// @Router /bicycles/{id}/images/{imageId} [patch]
+func (c *ImageController) Update(ctx *gin.Context) {
+	bicycleID, imageID, ok := imageIDs(ctx)
+	if !ok {
+		return
+	}
+
+	var input models.ImageUpdateInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	bicycle, err := c.imageService.UpdateImage(ctx.Request.Context(), bicycleID, imageID, input)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Image not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Failed to update image: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Image updated successfully",
+		Data:    bicycle,
+	})
+}
+
+// Reorder godoc
+// @Summary Reorder a bicycle's images
+// @Description Set the gallery order; image_ids must list every image once (Admin only)
+// @Tags bicycles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param input body models.ImageOrderInput true "Image IDs in the new order"
+// @Success 200 {object} models.APIResponse{data=models.Bicycle}
+// @Failure 400 {object} models.APIResponse
+// @Router /bicycles/{id}/images/order [put]
+func (c *ImageController) Reorder(ctx *gin.Context) {
+	bicycleID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return
+	}
+
+	var input models.ImageOrderInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	bicycle, err := c.imageService.ReorderImages(ctx.Request.Context(), bicycleID, input.ImageIDs)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Bicycle not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Failed to reorder images: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Images reordered successfully",
+		Data:    bicycle,
+	})
+}
+
+// Delete godoc
+// @Summary Delete a bicycle image
+// @Description Remove an image and its files; the next image becomes primary if needed (Admin only)
+// @Tags bicycles
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param imageId path string true "Image ID"
+// @Success 200 {object} models.APIResponse{data=models.Bicycle}
+// @Failure 404 {object} models.APIResponse
+// @Router /bicycles/{id}/images/{imageId} [delete]
+func (c *ImageController) Delete(ctx *gin.Context) {
+	bicycleID, imageID, ok := imageIDs(ctx)
+	if !ok {
+		return
+	}
+
+	bicycle, err := c.imageService.DeleteImage(ctx.Request.Context(), bicycleID, imageID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Image not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to delete image",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Image deleted successfully",
+		Data:    bicycle,
+	})
+}
+
+// imageIDs parses the bicycle and image IDs from the path, responding with 400 when either is invalid
+func imageIDs(ctx *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	bicycleID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	imageID, err := primitive.ObjectIDFromHex(ctx.Param("imageId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid image ID",
+		})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return bicycleID, imageID, true
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"bicycle-store/internal/models"
 	"bicycle-store/internal/services"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -24,6 +25,7 @@ func NewOrderController() *OrderController {
 // @Tags orders
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Param status query string false "Filter by status"
@@ -75,6 +77,7 @@ func (c *OrderController) GetAll(ctx *gin.Context) {
 // @Tags orders
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Param status query string false "Filter by status"
@@ -127,6 +130,7 @@ func (c *OrderController) GetMyOrders(ctx *gin.Context) {
 // @Tags orders
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path string true "Order ID"
 // @Success 200 {object} models.APIResponse{data=models.Order}
 // @Failure 404 {object} models.APIResponse
@@ -143,10 +147,10 @@ func (c *OrderController) GetByID(ctx *gin.Context) {
 		return
 	}
 
-	// Check if user is admin or order owner
+	// Check if user is admin, an API key scoped to read orders, or order owner
 	role, _ := ctx.Get("role")
 	userID, _ := ctx.Get("userID")
-	if role != "admin" && order.CustomerID.Hex() != userID.(string) {
+	if role != "admin" && role != "api_key" && order.CustomerID.Hex() != userID.(string) {
 		ctx.JSON(http.StatusForbidden, models.APIResponse{
 			Success: false,
 			Error:   "Access denied",
@@ -167,6 +171,7 @@ func (c *OrderController) GetByID(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param input body models.OrderInput true "Order data"
 // @Success 201 {object} models.APIResponse{data=models.Order}
 // @Failure 400 {object} models.APIResponse
@@ -185,6 +190,16 @@ func (c *OrderController) Create(ctx *gin.Context) {
 
 	order, err := c.orderService.CreateOrder(ctx.Request.Context(), customerID.(string), input)
 	if err != nil {
+		// Customization errors also list the individual problems so the UI can highlight them
+		var customizationErr *services.CustomizationError
+		if errors.As(err, &customizationErr) {
+			ctx.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+				Data:    customizationErr.Problems,
+			})
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Error:   err.Error(),
@@ -206,6 +221,7 @@ func (c *OrderController) Create(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path string true "Order ID"
 // @Param input body models.OrderStatusInput true "New status"
 // @Success 200 {object} models.APIResponse{data=models.Order}
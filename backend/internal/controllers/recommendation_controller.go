@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RecommendationController struct {
+	recommendationService *services.RecommendationService
+}
+
+func NewRecommendationController() *RecommendationController {
+	return &RecommendationController{
+		recommendationService: services.NewRecommendationService(),
+	}
+}
+
+// GetRelated godoc
+// @Summary Get related bicycles
+// @Description Get bicycles similar to a bicycle (same category, close price, shared specifications) and bicycles frequently bought together with it. Recomputed periodically.
+// @Tags bicycles
+// @Produce json
+// @Param id path string true "Bicycle ID"
+// @Success 200 {object} models.APIResponse{data=models.RelatedBicycles}
+// @Failure 404 {object} models.APIResponse
+// @Router /bicycles/{id}/related [get]
+func (c *RecommendationController) GetRelated(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return
+	}
+
+	related, err := c.recommendationService.GetRelated(ctx.Request.Context(), id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Bicycle not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch related bicycles",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    related,
+	})
+}
+
+// GetRecommended godoc
+// @Summary Get bicycles recommended for you
+// @Description Get bicycles related to those in your orders, leaving out ones you ordered. Empty until you have ordered.
+// @Tags bicycles
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Maximum bicycles (max 20)" default(8)
+// @Success 200 {object} models.APIResponse{data=[]models.Bicycle}
+// @Router /customers/me/recommendations [get]
+func (c *RecommendationController) GetRecommended(ctx *gin.Context) {
+	customerID, ok := currentCustomerID(ctx)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "8"))
+	if err != nil || limit < 1 || limit > 20 {
+		limit = 8
+	}
+
+	bicycles, err := c.recommendationService.GetRecommendedFor(ctx.Request.Context(), customerID, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch recommendations",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    bicycles,
+	})
+}
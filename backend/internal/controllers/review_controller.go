@@ -0,0 +1,429 @@
+package controllers
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReviewController struct {
+	reviewService *services.ReviewService
+}
+
+func NewReviewController() *ReviewController {
+	return &ReviewController{
+		reviewService: services.NewReviewService(),
+	}
+}
+
+// List godoc
+// @Summary Get the reviews of a bicycle
+// @Description Get a page of the approved reviews of a bicycle, optionally only those with a given number of stars
+// @Tags reviews
+// @Produce json
+// @Param id path string true "Bicycle ID"
+// @Param rating query int false "Only reviews with this many stars (1-5)"
+// @Param sort query string false "helpful (default), newest or highest"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} models.PaginatedResponse{data=[]models.Review}
+// @Failure 404 {object} models.APIResponse
+// @Router /bicycles/{id}/reviews [get]
+func (c *ReviewController) List(ctx *gin.Context) {
+	bicycleID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return
+	}
+
+	var filter models.ReviewFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 50 {
+		filter.Limit = 10
+	}
+
+	reviews, total, err := c.reviewService.GetReviews(ctx.Request.Context(), bicycleID, filter)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Bicycle not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	totalPages := (total + int64(filter.Limit) - 1) / int64(filter.Limit)
+
+	ctx.JSON(http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       reviews,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// Create godoc
+// @Summary Add a review to a bicycle
+// @Description Add a customer review to a bicycle; it is shown once a moderator approves it. Customers have one review per bicycle, so reviewing again updates it. Reviews are marked verified_purchase when the customer has a delivered order of the bicycle
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param input body models.ReviewInput true "Review data"
+// @Success 200 {object} models.APIResponse{data=models.Review} "Existing review updated"
+// @Success 201 {object} models.APIResponse{data=models.Review}
+// @Failure 400 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse "Verified purchase required"
+// @Router /bicycles/{id}/reviews [post]
+func (c *ReviewController) Create(ctx *gin.Context) {
+	bicycleID := ctx.Param("id")
+	customerID, _ := ctx.Get("userID")
+
+	var input models.ReviewInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	review, created, err := c.reviewService.AddReview(ctx.Request.Context(), customerID.(string), bicycleID, input)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == services.ErrVerifiedPurchaseRequired {
+			status = http.StatusForbidden
+		}
+		ctx.JSON(status, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !created {
+		ctx.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Your existing review was updated and submitted for moderation",
+			Data:    review,
+		})
+		return
+	}
+	ctx.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Review submitted for moderation",
+		Data:    review,
+	})
+}
+
+// Update godoc
+// @Summary Edit a review
+// @Description Change the rating and comment of your own review; it is moderated again
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param reviewId path string true "Review ID"
+// @Param input body models.ReviewInput true "Review data"
+// @Success 200 {object} models.APIResponse{data=models.Review}
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /bicycles/{id}/reviews/{reviewId} [put]
+func (c *ReviewController) Update(ctx *gin.Context) {
+	bicycleID, reviewID, ok := reviewIDs(ctx)
+	if !ok {
+		return
+	}
+	customerID, _ := ctx.Get("userID")
+
+	var input models.ReviewInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	review, err := c.reviewService.UpdateReview(ctx.Request.Context(), customerID.(string), bicycleID, reviewID, input)
+	if err != nil {
+		respondReviewError(ctx, err, "Failed to update review")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Review updated and submitted for moderation",
+		Data:    review,
+	})
+}
+
+// Delete godoc
+// @Summary Delete a review
+// @Description Delete your own review; admins can delete any review
+// @Tags reviews
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param reviewId path string true "Review ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /bicycles/{id}/reviews/{reviewId} [delete]
+func (c *ReviewController) Delete(ctx *gin.Context) {
+	bicycleID, reviewID, ok := reviewIDs(ctx)
+	if !ok {
+		return
+	}
+	userID, _ := ctx.Get("userID")
+	role, _ := ctx.Get("role")
+
+	if err := c.reviewService.DeleteReview(ctx.Request.Context(), userID.(string), role == "admin", bicycleID, reviewID); err != nil {
+		respondReviewError(ctx, err, "Failed to delete review")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Review deleted successfully",
+	})
+}
+
+// MarkHelpful godoc
+// @Summary Mark a review helpful
+// @Description Vote an approved review helpful, once per customer; helpfulness orders the reviews by default
+// @Tags reviews
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param reviewId path string true "Review ID"
+// @Success 200 {object} models.APIResponse{data=models.Review}
+// @Failure 403 {object} models.APIResponse "Own review"
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse "Already voted"
+// @Router /bicycles/{id}/reviews/{reviewId}/helpful [post]
+func (c *ReviewController) MarkHelpful(ctx *gin.Context) {
+	c.vote(ctx, models.ReviewVoteHelpful, "", "Thanks for your feedback")
+}
+
+// Report godoc
+// @Summary Report a review
+// @Description Report an approved review for abuse, once per customer. Reviews with enough reports are hidden until a moderator decides on them
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param reviewId path string true "Review ID"
+// @Param input body models.ReviewReportInput false "Reason"
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse "Own review"
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse "Already reported"
+// @Router /bicycles/{id}/reviews/{reviewId}/report [post]
+func (c *ReviewController) Report(ctx *gin.Context) {
+	var input models.ReviewReportInput
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	c.vote(ctx, models.ReviewVoteReport, input.Reason, "Review reported, thank you")
+}
+
+// vote records a vote of the given kind for the review in the path
+func (c *ReviewController) vote(ctx *gin.Context, kind, reason, message string) {
+	bicycleID, reviewID, ok := reviewIDs(ctx)
+	if !ok {
+		return
+	}
+	customerID, _ := ctx.Get("userID")
+
+	review, err := c.reviewService.Vote(ctx.Request.Context(), customerID.(string), bicycleID, reviewID, kind, reason)
+	if err == services.ErrAlreadyVoted {
+		ctx.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		respondReviewError(ctx, err, "Failed to vote on review")
+		return
+	}
+
+	// Reporters don't learn whether their report hid the review
+	var data interface{} = review
+	if kind == models.ReviewVoteReport {
+		data = nil
+	}
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// GetQueue godoc
+// @Summary Get the review moderation queue
+// @Description Get reviews with a moderation status, oldest first (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending (default), flagged, approved or rejected"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} models.PaginatedResponse{data=[]models.ReviewQueueItem}
+// @Router /admin/reviews [get]
+func (c *ReviewController) GetQueue(ctx *gin.Context) {
+	var filter models.ReviewQueueFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	items, total, err := c.reviewService.GetModerationQueue(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	totalPages := (total + int64(filter.Limit) - 1) / int64(filter.Limit)
+
+	ctx.JSON(http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       items,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// Moderate godoc
+// @Summary Moderate a review
+// @Description Approve or reject a review (Admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param reviewId path string true "Review ID"
+// @Param input body models.ReviewModerationInput true "Moderation decision"
+// @Success 200 {object} models.APIResponse{data=models.Review}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/bicycles/{id}/reviews/{reviewId} [patch]
+func (c *ReviewController) Moderate(ctx *gin.Context) {
+	bicycleID, reviewID, ok := reviewIDs(ctx)
+	if !ok {
+		return
+	}
+	adminID, _ := ctx.Get("userID")
+
+	var input models.ReviewModerationInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	review, err := c.reviewService.ModerateReview(ctx.Request.Context(), adminID.(string), bicycleID, reviewID, input.Status)
+	if err != nil {
+		respondReviewError(ctx, err, "Failed to moderate review")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Review " + input.Status,
+		Data:    review,
+	})
+}
+
+// reviewIDs parses the bicycle and review IDs from the path, responding with 400 when either is invalid
+func reviewIDs(ctx *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	bicycleID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	reviewID, err := primitive.ObjectIDFromHex(ctx.Param("reviewId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid review ID",
+		})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return bicycleID, reviewID, true
+}
+
+func respondReviewError(ctx *gin.Context, err error, message string) {
+	switch {
+	case err == mongo.ErrNoDocuments:
+		ctx.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Error:   "Review not found",
+		})
+	case err == services.ErrReviewAccessDenied, err == services.ErrVerifiedPurchaseRequired, err == services.ErrOwnReview:
+		ctx.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   message + ": " + err.Error(),
+		})
+	}
+}
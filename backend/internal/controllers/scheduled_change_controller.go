@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ScheduledChangeController struct {
+	changeService *services.ScheduledChangeService
+}
+
+func NewScheduledChangeController() *ScheduledChangeController {
+	return &ScheduledChangeController{
+		changeService: services.NewScheduledChangeService(),
+	}
+}
+
+// GetAll godoc
+// @Summary Get scheduled catalog changes
+// @Description Get scheduled bicycle changes, soonest first (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param bicycle_id query string false "Only changes for this bicycle"
+// @Param status query string false "Only changes with this status (pending, applied, failed, cancelled)"
+// @Success 200 {object} models.APIResponse{data=[]models.ScheduledChange}
+// @Router /admin/scheduled-changes [get]
+func (c *ScheduledChangeController) GetAll(ctx *gin.Context) {
+	var filter models.ScheduledChangeFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	changes, err := c.changeService.GetChanges(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch scheduled changes",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    changes,
+	})
+}
+
+// Create godoc
+// @Summary Schedule a bicycle change
+// @Description Schedule a partial bicycle update, such as a price change or publishing a draft with status active, to go live at effective_at (Admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param input body models.ScheduledChangeInput true "Change set"
+// @Success 201 {object} models.APIResponse{data=models.ScheduledChange}
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/bicycles/{id}/scheduled-changes [post]
+func (c *ScheduledChangeController) Create(ctx *gin.Context) {
+	adminID, _ := ctx.Get("userID")
+
+	var input models.ScheduledChangeInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	change, err := c.changeService.ScheduleChange(ctx.Request.Context(), adminID.(string), ctx.Param("id"), input)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Failed to schedule change: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Change scheduled successfully",
+		Data:    change,
+	})
+}
+
+// Cancel godoc
+// @Summary Cancel a scheduled change
+// @Description Cancel a change that has not been applied yet (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Scheduled change ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/scheduled-changes/{id} [delete]
+func (c *ScheduledChangeController) Cancel(ctx *gin.Context) {
+	if err := c.changeService.CancelChange(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Pending change not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Scheduled change cancelled",
+	})
+}
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type WishlistController struct {
+	wishlistService *services.WishlistService
+}
+
+func NewWishlistController() *WishlistController {
+	return &WishlistController{
+		wishlistService: services.NewWishlistService(),
+	}
+}
+
+// GetWishlist godoc
+// @Summary Get your wishlist
+// @Description Get the bicycles on your wishlist, most recently added first, with whether you have a stock alert on each
+// @Tags wishlist
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.WishlistEntry}
+// @Router /customers/me/wishlist [get]
+func (c *WishlistController) GetWishlist(ctx *gin.Context) {
+	customerID, ok := currentCustomerID(ctx)
+	if !ok {
+		return
+	}
+
+	entries, err := c.wishlistService.GetWishlist(ctx.Request.Context(), customerID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch wishlist",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    entries,
+	})
+}
+
+// AddToWishlist godoc
+// @Summary Add a bicycle to your wishlist
+// @Description Save a bicycle for later; adding one that is already on the wishlist does nothing
+// @Tags wishlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body models.WishlistInput true "Bicycle"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse "Wishlist full"
+// @Router /customers/me/wishlist [post]
+func (c *WishlistController) AddToWishlist(ctx *gin.Context) {
+	customerID, ok := currentCustomerID(ctx)
+	if !ok {
+		return
+	}
+
+	var input models.WishlistInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := c.wishlistService.AddToWishlist(ctx.Request.Context(), customerID, input.BicycleID); err != nil {
+		respondWishlistError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Added to wishlist",
+	})
+}
+
+// RemoveFromWishlist godoc
+// @Summary Remove a bicycle from your wishlist
+// @Tags wishlist
+// @Produce json
+// @Security BearerAuth
+// @Param bicycleId path string true "Bicycle ID"
+// @Success 200 {object} models.APIResponse
+// @Router /customers/me/wishlist/{bicycleId} [delete]
+func (c *WishlistController) RemoveFromWishlist(ctx *gin.Context) {
+	customerID, ok := currentCustomerID(ctx)
+	if !ok {
+		return
+	}
+
+	bicycleID, err := primitive.ObjectIDFromHex(ctx.Param("bicycleId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return
+	}
+
+	if err := c.wishlistService.RemoveFromWishlist(ctx.Request.Context(), customerID, bicycleID); err != nil {
+		respondWishlistError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Removed from wishlist",
+	})
+}
+
+// SubscribeStockAlert godoc
+// @Summary Get notified when a bicycle is back in stock
+// @Description Subscribe to a sold-out bicycle, or to a sold-out variant of a bicycle with variants; a notification is queued once when its stock goes up from 0
+// @Tags wishlist
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param sku query string false "Variant SKU, required for bicycles with variants"
+// @Success 201 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse "In stock or already subscribed"
+// @Router /bicycles/{id}/stock-alert [post]
+func (c *WishlistController) SubscribeStockAlert(ctx *gin.Context) {
+	customerID, bicycleID, ok := stockAlertIDs(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.wishlistService.SubscribeStockAlert(ctx.Request.Context(), customerID, bicycleID, ctx.Query("sku")); err != nil {
+		respondWishlistError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "You will be notified when this bicycle is back in stock",
+	})
+}
+
+// UnsubscribeStockAlert godoc
+// @Summary Cancel a back-in-stock alert
+// @Tags wishlist
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Bicycle ID"
+// @Param sku query string false "Variant SKU of the alert"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /bicycles/{id}/stock-alert [delete]
+func (c *WishlistController) UnsubscribeStockAlert(ctx *gin.Context) {
+	customerID, bicycleID, ok := stockAlertIDs(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.wishlistService.UnsubscribeStockAlert(ctx.Request.Context(), customerID, bicycleID, ctx.Query("sku")); err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "No stock alert on this bicycle",
+			})
+			return
+		}
+		respondWishlistError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Stock alert cancelled",
+	})
+}
+
+// currentCustomerID parses the authenticated user's ID, responding with 400 when it is invalid
+func currentCustomerID(ctx *gin.Context) (primitive.ObjectID, bool) {
+	userID, _ := ctx.Get("userID")
+	customerID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid customer ID",
+		})
+		return primitive.NilObjectID, false
+	}
+	return customerID, true
+}
+
+// stockAlertIDs parses the authenticated customer's ID and the bicycle ID from the path
+func stockAlertIDs(ctx *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	customerID, ok := currentCustomerID(ctx)
+	if !ok {
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	bicycleID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid bicycle ID",
+		})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return customerID, bicycleID, true
+}
+
+func respondWishlistError(ctx *gin.Context, err error) {
+	switch err {
+	case mongo.ErrNoDocuments:
+		ctx.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Error:   "Bicycle not found",
+		})
+	case services.ErrWishlistFull, services.ErrInStock, services.ErrAlreadySubscribed:
+		ctx.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+}
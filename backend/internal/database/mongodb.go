@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"log"
 	"time"
 
@@ -67,6 +68,32 @@ func createIndexes() error {
 		log.Printf("Warning: Failed to create email index: %v", err)
 	}
 
+	// Categories collection - unique URL slugs and subtree lookups
+	categoriesCollection := GetCollection("categories")
+	_, err = categoriesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"slug": bson.M{"$type": "string"},
+		}),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create categories.slug index: %v", err)
+	}
+
+	_, err = categoriesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "ancestors", Value: 1}},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create categories.ancestors index: %v", err)
+	}
+
+	_, err = categoriesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "parent_id", Value: 1}},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create categories.parent_id index: %v", err)
+	}
+
 	// Bicycles collection - category_id index
 	bicyclesCollection := GetCollection("bicycles")
 	_, err = bicyclesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
@@ -99,6 +126,71 @@ func createIndexes() error {
 		log.Printf("Warning: Failed to create text index: %v", err)
 	}
 
+	// Bicycles - unique variant SKUs across the catalog
+	_, err = bicyclesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "variants.sku", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"variants.sku": bson.M{"$exists": true},
+		}),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create variants.sku index: %v", err)
+	}
+
+	// Bicycles - unique supplier SKUs, matched by catalog imports
+	_, err = bicyclesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "sku", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"sku": bson.M{"$exists": true},
+		}),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create sku index: %v", err)
+	}
+
+	// Bicycles - category attribute filters
+	_, err = bicyclesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "attributes.name", Value: 1},
+			{Key: "attributes.value", Value: 1},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create attributes index: %v", err)
+	}
+
+	// Bicycles - status for catalog visibility, with deleted_at for purging archived bicycles
+	_, err = bicyclesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "deleted_at", Value: 1},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create status-deleted_at index: %v", err)
+	}
+
+	// Bicycles - rating filter and sorting
+	_, err = bicyclesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "rating_avg", Value: -1},
+			{Key: "rating_count", Value: -1},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create rating_avg-rating_count index: %v", err)
+	}
+
+	// Bicycles - numeric specification range filters and sorting
+	for _, field := range []string{"specifications.weight.value", "specifications.max_load.value", "specifications.gear_count"} {
+		_, err = bicyclesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: field, Value: 1}},
+		})
+		if err != nil {
+			log.Printf("Warning: Failed to create %s index: %v", field, err)
+		}
+	}
+
 	// Orders collection - compound index for customer and status
 	ordersCollection := GetCollection("orders")
 	_, err = ordersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
@@ -111,6 +203,17 @@ func createIndexes() error {
 		log.Printf("Warning: Failed to create customer_id-status index: %v", err)
 	}
 
+	// Orders - items.bicycle_id index for checking bicycles before deletion
+	_, err = ordersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "items.bicycle_id", Value: 1},
+			{Key: "status", Value: 1},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create items.bicycle_id-status index: %v", err)
+	}
+
 	// Orders - order_date index for sorting
 	_, err = ordersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
 		Keys: bson.D{{Key: "order_date", Value: -1}},
@@ -119,6 +222,166 @@ func createIndexes() error {
 		log.Printf("Warning: Failed to create order_date index: %v", err)
 	}
 
+	// Price history - a bicycle's changes, newest first
+	_, err = GetCollection("price_history").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "bicycle_id", Value: 1},
+			{Key: "changed_at", Value: -1},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create price_history bicycle_id-changed_at index: %v", err)
+	}
+
+	// Reviews - a bicycle's reviews, one per customer
+	reviewsCollection := GetCollection("reviews")
+	_, err = reviewsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "bicycle_id", Value: 1},
+			{Key: "customer_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create reviews bicycle_id-customer_id index: %v", err)
+	}
+
+	// Reviews - a customer's reviews
+	_, err = reviewsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "customer_id", Value: 1}},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create reviews customer_id index: %v", err)
+	}
+
+	// Reviews - the approved reviews of a bicycle, newest first
+	_, err = reviewsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "bicycle_id", Value: 1},
+			{Key: "status", Value: 1},
+			{Key: "review_date", Value: -1},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create reviews bicycle_id-status-review_date index: %v", err)
+	}
+
+	// Reviews - moderation queue, oldest first
+	_, err = reviewsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "review_date", Value: 1},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create reviews status-review_date index: %v", err)
+	}
+
+	// Review votes - one helpful vote and one report per customer and review
+	_, err = GetCollection("review_votes").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "review_id", Value: 1},
+			{Key: "customer_id", Value: 1},
+			{Key: "kind", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create review_votes review_id-customer_id-kind index: %v", err)
+	}
+
+	// Stock alerts - one waiting alert per customer and bicycle or variant, found by
+	// bicycle on restock. It replaces an index without the SKU.
+	stockAlertsCollection := GetCollection("stock_alerts")
+	if _, err = stockAlertsCollection.Indexes().DropOne(ctx, "bicycle_id_1_customer_id_1"); err != nil && !isNotFound(err) {
+		log.Printf("Warning: Failed to drop stock_alerts bicycle_id-customer_id index: %v", err)
+	}
+	_, err = stockAlertsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "bicycle_id", Value: 1},
+			{Key: "sku", Value: 1},
+			{Key: "customer_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"status": "waiting",
+		}),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create stock_alerts bicycle_id-sku-customer_id index: %v", err)
+	}
+
+	// Notifications - pending notifications for the mailer, oldest first
+	_, err = GetCollection("notifications").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "sent_at", Value: 1},
+			{Key: "created_at", Value: 1},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create notifications sent_at-created_at index: %v", err)
+	}
+
+	// Scheduled changes - the scheduler claims due pending changes in order
+	_, err = GetCollection("scheduled_changes").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "effective_at", Value: 1},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create scheduled_changes status-effective_at index: %v", err)
+	}
+
+	// Import jobs - listed newest first
+	_, err = GetCollection("import_jobs").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create import_jobs created_at index: %v", err)
+	}
+
+	// API keys - unique key_hash index for authentication lookups
+	_, err = GetCollection("api_keys").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create key_hash index: %v", err)
+	}
+
+	// OIDC states - TTL index so abandoned logins are cleaned up
+	_, err = GetCollection("oidc_states").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create oidc_states TTL index: %v", err)
+	}
+
+	// Customers - sparse unique index on the linked identity provider subject
+	_, err = customersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "oidc_subject", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create oidc_subject index: %v", err)
+	}
+
+	// Rate limits - TTL index so idle buckets expire on their own
+	_, err = GetCollection("rate_limits").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create rate_limits TTL index: %v", err)
+	}
+
 	log.Println("Database indexes created successfully")
 	return nil
 }
+
+// isNotFound reports whether a command failed because its collection or index doesn't exist
+func isNotFound(err error) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && (commandErr.Code == 26 || commandErr.Code == 27)
+}
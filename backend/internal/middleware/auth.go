@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"bicycle-store/internal/models"
+	"bicycle-store/internal/services"
 	"bicycle-store/internal/utils"
 	"net/http"
 	"strings"
@@ -9,8 +10,43 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware authenticates a JWT bearer token or, on routes that list scopes,
+// an X-API-Key holding at least one of those scopes.
+func AuthMiddleware(scopes ...string) gin.HandlerFunc {
+	apiKeyService := services.NewAPIKeyService()
+
 	return func(c *gin.Context) {
+		// Machine-to-machine integrations authenticate with an API key instead of a JWT
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			key, err := authenticateAPIKey(c, apiKeyService, apiKey)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, models.APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				c.Abort()
+				return
+			}
+
+			if !hasAnyScope(key.Scopes, scopes) {
+				c.JSON(http.StatusForbidden, models.APIResponse{
+					Success: false,
+					Error:   "API key is not allowed to access this resource",
+				})
+				c.Abort()
+				return
+			}
+
+			// Act on behalf of the key owner
+			c.Set("userID", key.OwnerID.Hex())
+			c.Set("role", "api_key")
+			c.Set("apiKeyID", key.ID.Hex())
+			c.Set("scopes", key.Scopes)
+
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, models.APIResponse{
@@ -52,10 +88,11 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// AdminMiddleware allows admins, and API keys that AuthMiddleware already scoped to the route
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
-		if !exists || role != "admin" {
+		if !exists || (role != "admin" && role != "api_key") {
 			c.JSON(http.StatusForbidden, models.APIResponse{
 				Success: false,
 				Error:   "Admin access required",
@@ -67,3 +104,29 @@ func AdminMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func hasAnyScope(granted, required []string) bool {
+	for _, r := range required {
+		for _, g := range granted {
+			if g == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authenticateAPIKey authenticates a plaintext API key once per request; the rate limiter
+// and AuthMiddleware both need it
+func authenticateAPIKey(c *gin.Context, apiKeyService *services.APIKeyService, plaintext string) (*models.APIKey, error) {
+	if key, exists := c.Get("apiKey"); exists {
+		return key.(*models.APIKey), nil
+	}
+
+	key, err := apiKeyService.Authenticate(c.Request.Context(), plaintext)
+	if err != nil {
+		return nil, err
+	}
+	c.Set("apiKey", key)
+	return key, nil
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"bicycle-store/internal/config"
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"bicycle-store/internal/services"
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitPolicy describes a token bucket: Burst tokens at most, refilled at
+// Rate tokens per second. Requests are bucketed by the value returned by Key.
+type RateLimitPolicy struct {
+	Name  string
+	Rate  float64
+	Burst int
+	Key   func(c *gin.Context) string
+}
+
+// RateLimitResult is the outcome of taking one token from a bucket
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// RateLimitStore keeps bucket state. Implementations must be safe for concurrent use.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error)
+}
+
+// KeyByIP buckets requests by client IP
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser buckets requests by authenticated user, falling back to client IP.
+// It must run after AuthMiddleware.
+func KeyByUser(c *gin.Context) string {
+	if userID, exists := c.Get("userID"); exists {
+		if id, ok := userID.(string); ok && id != "" {
+			return "user:" + id
+		}
+	}
+	return KeyByIP(c)
+}
+
+// KeyByAPIKey buckets requests by their X-API-Key, falling back to client IP. Only keys
+// that authenticate get a bucket of their own, so that made-up keys can't be used to
+// dodge the limit.
+func KeyByAPIKey() func(c *gin.Context) string {
+	apiKeyService := services.NewAPIKeyService()
+
+	return func(c *gin.Context) string {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			if key, err := authenticateAPIKey(c, apiKeyService, apiKey); err == nil {
+				return "key:" + key.ID.Hex()
+			}
+		}
+		return KeyByIP(c)
+	}
+}
+
+func RateLimitMiddleware(store RateLimitStore, policy RateLimitPolicy) gin.HandlerFunc {
+	if policy.Key == nil {
+		policy.Key = KeyByIP
+	}
+
+	return func(c *gin.Context) {
+		key := policy.Name + ":" + policy.Key(c)
+
+		result, err := store.Take(c.Request.Context(), key, policy)
+		if err != nil {
+			// Fail open so a store outage doesn't take the API down with it
+			log.Printf("Rate limit store error: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(policy.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Header("RateLimit-Policy", strconv.Itoa(policy.Burst)+";w="+strconv.Itoa(ceilSeconds(refillDuration(policy, float64(policy.Burst)))))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, models.APIResponse{
+				Success: false,
+				Error:   "Too many requests, please try again later",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// refillDuration returns how long the policy takes to refill the given number of tokens
+func refillDuration(policy RateLimitPolicy, tokens float64) time.Duration {
+	if tokens <= 0 || policy.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / policy.Rate * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// BucketResult converts the token count left in a bucket after a take into a RateLimitResult
+func BucketResult(policy RateLimitPolicy, tokens float64, allowed bool) RateLimitResult {
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     refillDuration(policy, float64(policy.Burst)-tokens),
+	}
+	if !allowed {
+		result.RetryAfter = refillDuration(policy, 1-tokens)
+	}
+	return result
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryRateLimitStore keeps buckets in process memory. Use it for single-instance deployments.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(policy.Burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	// Refill based on time elapsed since the last request
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	bucket.tokens = math.Min(float64(policy.Burst), bucket.tokens+elapsed*policy.Rate)
+	bucket.updatedAt = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	return BucketResult(policy, bucket.tokens, allowed), nil
+}
+
+// sweep drops buckets that have been idle for a while; any of them would be full again by now
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updatedAt) > time.Hour {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// MongoRateLimitStore keeps buckets in MongoDB so that every API instance shares them
+type MongoRateLimitStore struct {
+	repo *repositories.RateLimitRepository
+}
+
+func NewMongoRateLimitStore() *MongoRateLimitStore {
+	return &MongoRateLimitStore{
+		repo: repositories.NewRateLimitRepository(),
+	}
+}
+
+func (s *MongoRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	tokens, allowed, err := s.repo.Take(ctx, key, policy.Rate, policy.Burst)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	return BucketResult(policy, tokens, allowed), nil
+}
+
+// NewRateLimitStore returns the store selected by the RATE_LIMIT_STORE setting
+func NewRateLimitStore() RateLimitStore {
+	if config.AppConfig.RateLimitStore == "mongo" {
+		return NewMongoRateLimitStore()
+	}
+	return NewMemoryRateLimitStore()
+}
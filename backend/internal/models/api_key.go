@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// API key scopes. Each scope grants an API key access to the routes that list it.
+const (
+	ScopeStockWrite  = "stock:write"  // PATCH /bicycles/:id/stock
+	ScopeOrdersRead  = "orders:read"  // list and view all orders
+	ScopeOrdersWrite = "orders:write" // create orders and update their status
+)
+
+var ValidScopes = map[string]bool{
+	ScopeStockWrite:  true,
+	ScopeOrdersRead:  true,
+	ScopeOrdersWrite: true,
+}
+
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	KeyHash    string             `bson:"key_hash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	OwnerID    primitive.ObjectID `bson:"owner_id" json:"owner_id"` // customer that orders placed with this key belong to
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type APIKeyInput struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	OwnerID   string     `json:"owner_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyCreatedResponse carries the plaintext key. It is only ever returned once, on creation.
+type APIKeyCreatedResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
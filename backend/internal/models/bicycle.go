@@ -1,76 +1,377 @@
 package models
 
 import (
+	"math"
+	"sort"
+	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Specifications stores weight and max load in kilograms and wheel size in inches
 type Specifications struct {
-	FrameMaterial string `bson:"frame_material" json:"frame_material"`
-	WheelSize     string `bson:"wheel_size" json:"wheel_size"`
-	GearCount     int    `bson:"gear_count" json:"gear_count"`
-	BrakeType     string `bson:"brake_type" json:"brake_type"`
-	Suspension    string `bson:"suspension" json:"suspension"`
-	Weight        string `bson:"weight" json:"weight"`
-	MaxLoad       string `bson:"max_load" json:"max_load"`
+	FrameMaterial string      `bson:"frame_material" json:"frame_material"`
+	WheelSize     Measurement `bson:"wheel_size" json:"wheel_size"`
+	GearCount     int         `bson:"gear_count" json:"gear_count"`
+	BrakeType     string      `bson:"brake_type" json:"brake_type"`
+	Suspension    string      `bson:"suspension" json:"suspension"`
+	Weight        Measurement `bson:"weight" json:"weight"`
+	MaxLoad       Measurement `bson:"max_load" json:"max_load"`
 }
 
 type CustomizationOption struct {
-	Name    string   `bson:"name" json:"name"`       // e.g., "frame_color", "wheel_size", "accessories"
-	Options []string `bson:"options" json:"options"` // e.g., ["Red", "Blue", "Black"]
+	Name       string             `bson:"name" json:"name"`                                 // e.g., "frame_color", "wheel_size", "accessories"
+	Options    []string           `bson:"options" json:"options"`                           // e.g., ["Red", "Blue", "Black"]
+	Required   bool               `bson:"required" json:"required"`                         // must be selected when ordering
+	Multiple   bool               `bson:"multiple" json:"multiple"`                         // may be selected more than once, e.g. accessories
+	Surcharges map[string]float64 `bson:"surcharges,omitempty" json:"surcharges,omitempty"` // extra price per value, e.g. {"Gold": 15000}
 }
 
-type Review struct {
-	ReviewID     primitive.ObjectID `bson:"review_id" json:"review_id"`
-	CustomerID   primitive.ObjectID `bson:"customer_id" json:"customer_id"`
-	CustomerName string             `bson:"customer_name" json:"customer_name"`
-	Rating       int                `bson:"rating" json:"rating"`
-	Comment      string             `bson:"comment" json:"comment"`
-	ReviewDate   time.Time          `bson:"review_date" json:"review_date"`
+// Variant is a purchasable combination of customization option values with its own SKU and stock.
+// Bicycles without variants are sold as a single product using the parent price and stock.
+type Variant struct {
+	VariantID     primitive.ObjectID      `bson:"variant_id" json:"variant_id"`
+	SKU           string                  `bson:"sku" json:"sku"`
+	Options       []SelectedCustomization `bson:"options" json:"options"`                 // e.g., [{frame_color Red} {wheel_size 29"}]
+	Price         *float64                `bson:"price,omitempty" json:"price,omitempty"` // overrides the bicycle price when set
+	StockQuantity int                     `bson:"stock_quantity" json:"stock_quantity"`
+	Barcode       string                  `bson:"barcode,omitempty" json:"barcode,omitempty"`
 }
 
+// Matches reports whether the selected customizations include every option value of the variant
+func (v *Variant) Matches(selected []SelectedCustomization) bool {
+	for _, option := range v.Options {
+		found := false
+		for _, s := range selected {
+			if s.Name == option.Name && s.Value == option.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// BicycleImage is an uploaded gallery image. The gallery is shown in array
+// order and the primary image also becomes the bicycle's image_url.
+type BicycleImage struct {
+	ImageID     primitive.ObjectID `bson:"image_id" json:"image_id"`
+	URL         string             `bson:"url" json:"url"` // the original upload
+	AltText     string             `bson:"alt_text" json:"alt_text"`
+	Primary     bool               `bson:"primary" json:"primary"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Width       int                `bson:"width" json:"width"`
+	Height      int                `bson:"height" json:"height"`
+	Renditions  []ImageRendition   `bson:"renditions" json:"renditions"`
+	BlobKeys    []string           `bson:"blob_keys" json:"-"` // every stored file, for deletion
+	UploadedAt  time.Time          `bson:"uploaded_at" json:"uploaded_at"`
+}
+
+// ImageRendition is a downscaled copy of a gallery image, e.g. "thumbnail" or "medium".
+// URL has the original's format (PNG for WebP uploads) and WebPURL a WebP copy.
+type ImageRendition struct {
+	Size    string `bson:"size" json:"size"`
+	Width   int    `bson:"width" json:"width"`
+	Height  int    `bson:"height" json:"height"`
+	URL     string `bson:"url" json:"url"`
+	WebPURL string `bson:"webp_url" json:"webp_url"`
+}
+
+// DisplayURL is the URL used as the bicycle's image_url when this image is primary
+func (i *BicycleImage) DisplayURL() string {
+	for _, rendition := range i.Renditions {
+		if rendition.Size == "medium" {
+			return rendition.URL
+		}
+	}
+	return i.URL
+}
+
+type ImageUpdateInput struct {
+	AltText *string `json:"alt_text"`
+	Primary *bool   `json:"primary"` // only true is accepted; another image must be made primary instead
+}
+
+type ImageOrderInput struct {
+	ImageIDs []string `json:"image_ids" binding:"required,min=1"` // every image of the gallery in the new order
+}
+
+// EmptyRatingHistogram returns a star histogram with no reviews
+func EmptyRatingHistogram() map[string]int {
+	return map[string]int{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0}
+}
+
+// SummarizeReviews sets the rating aggregates and top reviews from all reviews of the bicycle
+func (b *Bicycle) SummarizeReviews(reviews []Review) {
+	b.RatingCount, b.RatingSum, b.RatingAvg = 0, 0, 0
+	b.RatingHistogram = EmptyRatingHistogram()
+	b.TopReviews = []Review{}
+	for _, review := range reviews {
+		if review.IsApproved() {
+			b.RatingCount++
+			b.RatingSum += review.Rating
+			b.RatingHistogram[strconv.Itoa(review.Rating)]++
+			b.TopReviews = append(b.TopReviews, review)
+		}
+	}
+	if b.RatingCount > 0 {
+		b.RatingAvg = math.Round(float64(b.RatingSum)/float64(b.RatingCount)*100) / 100
+	}
+
+	// Same order as the helpful sort of the reviews listing
+	sort.SliceStable(b.TopReviews, func(i, j int) bool {
+		a, c := b.TopReviews[i], b.TopReviews[j]
+		if a.HelpfulCount != c.HelpfulCount {
+			return a.HelpfulCount > c.HelpfulCount
+		}
+		return a.ReviewDate.After(c.ReviewDate)
+	})
+	if len(b.TopReviews) > TopReviewCount {
+		b.TopReviews = b.TopReviews[:TopReviewCount]
+	}
+}
+
+// Bicycle lifecycle statuses. Only active bicycles are shown in the public
+// catalog; deleting a bicycle archives it so orders and reports keep its data.
+const (
+	BicycleStatusDraft    = "draft"
+	BicycleStatusActive   = "active"
+	BicycleStatusArchived = "archived"
+)
+
 type Bicycle struct {
 	ID                   primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	SKU                  string                `bson:"sku,omitempty" json:"sku,omitempty"` // supplier stock keeping unit, unique across the catalog
 	ModelName            string                `bson:"model_name" json:"model_name" binding:"required"`
 	Brand                string                `bson:"brand" json:"brand" binding:"required"`
-	Price                float64               `bson:"price" json:"price" binding:"required"`
+	Price                float64               `bson:"price" json:"price"`
 	StockQuantity        int                   `bson:"stock_quantity" json:"stock_quantity"`
 	CategoryID           primitive.ObjectID    `bson:"category_id" json:"category_id" binding:"required"`
 	Specifications       Specifications        `bson:"specifications" json:"specifications"`
+	Attributes           []AttributeValue      `bson:"attributes" json:"attributes"`
 	CustomizationOptions []CustomizationOption `bson:"customization_options" json:"customization_options"`
 	Description          string                `bson:"description" json:"description"`
-	ImageURL             string                `bson:"image_url" json:"image_url"`
-	Reviews              []Review              `bson:"reviews" json:"reviews"`
-	CreatedAt            time.Time             `bson:"created_at" json:"created_at"`
-	UpdatedAt            time.Time             `bson:"updated_at" json:"updated_at"`
+	ImageURL             string                `bson:"image_url" json:"image_url"` // the primary gallery image when the bicycle has a gallery
+	Images               []BicycleImage        `bson:"images,omitempty" json:"images,omitempty"`
+	Variants             []Variant             `bson:"variants" json:"variants"`
+	TopReviews           []Review              `bson:"top_reviews" json:"top_reviews,omitempty"` // most helpful approved reviews, left out of listings
+	// Rating aggregates of approved reviews, kept in step with every review change
+	RatingAvg       float64        `bson:"rating_avg" json:"rating_avg"` // 0 when unrated
+	RatingCount     int            `bson:"rating_count" json:"rating_count"`
+	RatingSum       int            `bson:"rating_sum" json:"-"`
+	RatingHistogram map[string]int `bson:"rating_histogram" json:"rating_histogram"` // reviews per star, "1" to "5"
+	Sales           []SalePrice    `bson:"sales,omitempty" json:"sales,omitempty"`
+	CompareAtPrice  *float64       `bson:"-" json:"compare_at_price,omitempty"` // regular price while a sale is active
+	Status          string         `bson:"status" json:"status"`
+	DeletedAt       *time.Time     `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // when the bicycle was archived
+	CreatedAt       time.Time      `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+// IsPublic reports whether the bicycle is shown in the public catalog.
+// Bicycles created before statuses existed have none and count as active.
+func (b *Bicycle) IsPublic() bool {
+	return b.Status == BicycleStatusActive || b.Status == ""
+}
+
+// SalePrice is a time-boxed sale price, active from StartsAt until EndsAt
+type SalePrice struct {
+	Price    float64   `bson:"price" json:"price"`
+	StartsAt time.Time `bson:"starts_at" json:"starts_at"`
+	EndsAt   time.Time `bson:"ends_at" json:"ends_at"`
+}
+
+// ActiveSale returns the sale running at now, or nil
+func (b *Bicycle) ActiveSale(now time.Time) *SalePrice {
+	for i := range b.Sales {
+		sale := b.Sales[i]
+		if !now.Before(sale.StartsAt) && now.Before(sale.EndsAt) {
+			return &sale
+		}
+	}
+	return nil
+}
+
+// ApplySale prices the bicycle for shoppers at now. While a sale runs, Price is
+// the sale price and CompareAtPrice the regular price, and variants with their
+// own price get the same discount, to the cent. The sale schedule itself is left out.
+func (b *Bicycle) ApplySale(now time.Time) {
+	sale := b.ActiveSale(now)
+	b.Sales = nil
+	if sale == nil || sale.Price >= b.Price {
+		return
+	}
+
+	regular := b.Price
+	ratio := sale.Price / regular
+	b.CompareAtPrice = &regular
+	b.Price = sale.Price
+	for i := range b.Variants {
+		if b.Variants[i].Price != nil {
+			discounted := round2(*b.Variants[i].Price * ratio)
+			b.Variants[i].Price = &discounted
+		}
+	}
+}
+
+// AttributeValue is the value of a category-defined attribute. Attributes are
+// stored as name/value pairs so one index serves filtering on any of them.
+type AttributeValue struct {
+	Name  string      `bson:"name" json:"name"`
+	Value interface{} `bson:"value" json:"value"`
+	Unit  string      `bson:"unit,omitempty" json:"unit,omitempty"`
 }
 
 type BicycleInput struct {
-	ModelName            string                `json:"model_name" binding:"required"`
-	Brand                string                `json:"brand" binding:"required"`
-	Price                float64               `json:"price" binding:"required"`
-	StockQuantity        int                   `json:"stock_quantity"`
-	CategoryID           string                `json:"category_id" binding:"required"`
-	Specifications       Specifications        `json:"specifications"`
-	CustomizationOptions []CustomizationOption `json:"customization_options"`
-	Description          string                `json:"description"`
-	ImageURL             string                `json:"image_url"`
+	SKU                  string                 `json:"sku"` // unchanged on update when empty
+	ModelName            string                 `json:"model_name" binding:"required"`
+	Brand                string                 `json:"brand" binding:"required"`
+	Price                float64                `json:"price" binding:"required"`
+	StockQuantity        int                    `json:"stock_quantity"`
+	CategoryID           string                 `json:"category_id" binding:"required"`
+	Specifications       Specifications         `json:"specifications"`
+	Attributes           map[string]interface{} `json:"attributes"` // values for the category's attribute schema
+	CustomizationOptions []CustomizationOption  `json:"customization_options"`
+	Description          string                 `json:"description"`
+	ImageURL             string                 `json:"image_url"`
+	Variants             []VariantInput         `json:"variants"`
+	Status               string                 `json:"status"` // draft or active; active when created without one, unchanged on update
+	Sales                []SalePrice            `json:"sales"`  // scheduled sale prices; unchanged on update when omitted
+}
+
+type VariantInput struct {
+	SKU           string                  `json:"sku" binding:"required"`
+	Options       []SelectedCustomization `json:"options" binding:"required,min=1"`
+	Price         *float64                `json:"price"`
+	StockQuantity int                     `json:"stock_quantity" binding:"min=0"`
+	Barcode       string                  `json:"barcode"`
+}
+
+// VariantAvailability is a variant as shown in the catalog, with its effective price
+type VariantAvailability struct {
+	VariantID     primitive.ObjectID      `json:"variant_id"`
+	SKU           string                  `json:"sku"`
+	Options       []SelectedCustomization `json:"options"`
+	Price         float64                 `json:"price"`
+	StockQuantity int                     `json:"stock_quantity"`
+	InStock       bool                    `json:"in_stock"`
+}
+
+// FindVariant returns the variant matching the selected customizations, or nil if none does.
+// Variants all set the same options; of variants saved before that was required, the one
+// setting the most options wins.
+func (b *Bicycle) FindVariant(selected []SelectedCustomization) *Variant {
+	var found *Variant
+	for i := range b.Variants {
+		if b.Variants[i].Matches(selected) && (found == nil || len(b.Variants[i].Options) > len(found.Options)) {
+			found = &b.Variants[i]
+		}
+	}
+	return found
 }
 
-type ReviewInput struct {
-	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
-	Comment string `json:"comment" binding:"required"`
+// FindVariantBySKU returns the variant with the given SKU, or nil if there is none
+func (b *Bicycle) FindVariantBySKU(sku string) *Variant {
+	for i := range b.Variants {
+		if b.Variants[i].SKU == sku {
+			return &b.Variants[i]
+		}
+	}
+	return nil
+}
+
+// VariantPrice returns the price of a variant, falling back to the bicycle price
+func (b *Bicycle) VariantPrice(variant *Variant) float64 {
+	if variant != nil && variant.Price != nil {
+		return *variant.Price
+	}
+	return b.Price
+}
+
+// Availability lists every variant with its effective price and stock status
+func (b *Bicycle) Availability() []VariantAvailability {
+	availability := make([]VariantAvailability, 0, len(b.Variants))
+	for i := range b.Variants {
+		variant := &b.Variants[i]
+		availability = append(availability, VariantAvailability{
+			VariantID:     variant.VariantID,
+			SKU:           variant.SKU,
+			Options:       variant.Options,
+			Price:         b.VariantPrice(variant),
+			StockQuantity: variant.StockQuantity,
+			InStock:       variant.StockQuantity > 0,
+		})
+	}
+	return availability
 }
 
 type BicycleFilter struct {
-	CategoryID string  `form:"category_id"`
-	MinPrice   float64 `form:"min_price"`
-	MaxPrice   float64 `form:"max_price"`
-	Brand      string  `form:"brand"`
-	Search     string  `form:"search"`
-	Page       int     `form:"page,default=1"`
-	Limit      int     `form:"limit,default=10"`
-	Sort       string  `form:"sort,default=created_at"`
-	Order      string  `form:"order,default=desc"`
+	CategoryID string `form:"category_id"` // ID or slug; includes subcategories
+	// CategoryIDs is the category subtree resolved from CategoryID
+	CategoryIDs []primitive.ObjectID `form:"-"`
+	MinPrice    float64              `form:"min_price"`
+	MaxPrice    float64              `form:"max_price"`
+	Brand       string               `form:"brand"`
+	Search      string               `form:"search"`
+	// Specification filters; weight and load are in kilograms
+	MinWeight     float64 `form:"min_weight"`
+	MaxWeight     float64 `form:"max_weight"`
+	MinGears      int     `form:"min_gears"`
+	MaxGears      int     `form:"max_gears"`
+	MinLoad       float64 `form:"min_load"`
+	MaxLoad       float64 `form:"max_load"`
+	MinRating     float64 `form:"min_rating"` // average rating of approved reviews
+	BrakeType     string  `form:"brake_type"`
+	Suspension    string  `form:"suspension"`
+	FrameMaterial string  `form:"frame_material"`
+	// Category attribute filters, bound from attr[name]=value, attr_min[name] and attr_max[name]
+	Attributes   map[string]string  `form:"-"`
+	AttributeMin map[string]float64 `form:"-"`
+	AttributeMax map[string]float64 `form:"-"`
+	// IncludeHidden lists drafts and archived bicycles too, optionally only those with Status
+	IncludeHidden bool   `form:"-"`
+	Status        string `form:"status"`
+	Page          int    `form:"page,default=1"`
+	Limit         int    `form:"limit,default=10"`
+	Sort          string `form:"sort,default=created_at"` // a field name, weight, max_load, gear_count, rating, or relevance to rank search matches
+	Order         string `form:"order,default=desc"`
+}
+
+// SearchSuggestion is an autocomplete match; ID is set for models and categories
+type SearchSuggestion struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+}
+
+// FacetCount is the number of bicycles sharing one value of a facet
+type FacetCount struct {
+	Value interface{} `bson:"_id" json:"value"`
+	Label string      `bson:"label,omitempty" json:"label,omitempty"`
+	Count int         `bson:"count" json:"count"`
+}
+
+// PriceBucket counts bicycles priced from Min (inclusive) up to Max (exclusive)
+type PriceBucket struct {
+	Min   interface{} `bson:"_id" json:"min"`
+	Max   interface{} `bson:"max" json:"max,omitempty"`
+	Count int         `bson:"count" json:"count"`
+}
+
+// BicycleFacets holds filter counts for the catalog. Each facet respects every
+// active filter except its own, so the UI can show alternatives to a selection.
+type BicycleFacets struct {
+	Brands         []FacetCount  `bson:"brands" json:"brands"`
+	Categories     []FacetCount  `bson:"categories" json:"categories"`
+	FrameMaterials []FacetCount  `bson:"frame_materials" json:"frame_materials"`
+	WheelSizes     []FacetCount  `bson:"wheel_sizes" json:"wheel_sizes"`
+	BrakeTypes     []FacetCount  `bson:"brake_types" json:"brake_types"`
+	Suspensions    []FacetCount  `bson:"suspensions" json:"suspensions"`
+	GearCounts     []FacetCount  `bson:"gear_counts" json:"gear_counts"`
+	PriceRanges    []PriceBucket `bson:"price_ranges" json:"price_ranges"`
+	Ratings        []FacetCount  `bson:"ratings" json:"ratings"` // value is the whole-star average rating, null when unrated
 }
@@ -6,15 +6,66 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Attribute types a category schema can declare
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeInteger = "integer"
+	AttributeBoolean = "boolean"
+)
+
+// AttributeDefinition describes a bicycle attribute specific to a category, such
+// as an e-bike's battery capacity. Admin forms are rendered from these definitions.
+type AttributeDefinition struct {
+	Name          string   `bson:"name" json:"name"`                                         // e.g. "battery_capacity"
+	Label         string   `bson:"label" json:"label"`                                       // e.g. "Battery capacity"
+	Type          string   `bson:"type" json:"type"`                                         // string, number, integer or boolean
+	Unit          string   `bson:"unit,omitempty" json:"unit,omitempty"`                     // e.g. "Wh"
+	Required      bool     `bson:"required" json:"required"`                                 // must be set on every bicycle in the category
+	AllowedValues []string `bson:"allowed_values,omitempty" json:"allowed_values,omitempty"` // string attributes only, rendered as a select
+}
+
+// Category is a node in the category tree. Ancestors lists the IDs from the root
+// down to the parent, so a subtree is found with a single query on ancestors.
 type Category struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name        string             `bson:"category_name" json:"category_name" binding:"required"`
-	Description string             `bson:"description" json:"description"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID          primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	Name        string                `bson:"category_name" json:"category_name" binding:"required"`
+	Slug        string                `bson:"slug" json:"slug"`
+	ParentID    *primitive.ObjectID   `bson:"parent_id" json:"parent_id"`
+	Ancestors   []primitive.ObjectID  `bson:"ancestors" json:"ancestors"`
+	Description string                `bson:"description" json:"description"`
+	Attributes  []AttributeDefinition `bson:"attributes" json:"attributes"`
+	CreatedAt   time.Time             `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time             `bson:"updated_at" json:"updated_at"`
 }
 
 type CategoryInput struct {
-	Name        string `json:"category_name" binding:"required"`
-	Description string `json:"description"`
+	Name        string                `json:"category_name" binding:"required"`
+	Slug        string                `json:"slug"`      // generated from the name when empty
+	ParentID    string                `json:"parent_id"` // empty for a top-level category
+	Description string                `json:"description"`
+	Attributes  []AttributeDefinition `json:"attributes"`
+}
+
+// CategoryNode is a category with its subcategories, as returned by the tree endpoint
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// Breadcrumb is one step of the path from the root category to a category
+type Breadcrumb struct {
+	ID   primitive.ObjectID `json:"id"`
+	Name string             `json:"category_name"`
+	Slug string             `json:"slug"`
+}
+
+// FindAttribute returns the definition of a category attribute by name
+func (c *Category) FindAttribute(name string) *AttributeDefinition {
+	for i := range c.Attributes {
+		if c.Attributes[i].Name == name {
+			return &c.Attributes[i]
+		}
+	}
+	return nil
 }
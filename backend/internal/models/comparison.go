@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bounds on how many bicycles are compared side by side
+const (
+	MinCompareBicycles = 2
+	MaxCompareBicycles = 4
+)
+
+// Comparison row groups
+const (
+	ComparisonGroupOverview       = "overview"
+	ComparisonGroupSpecifications = "specifications"
+	ComparisonGroupAttributes     = "attributes"
+)
+
+// Comparison lays bicycles side by side; every row has one value per bicycle, in
+// the order of Bicycles
+type Comparison struct {
+	Bicycles []Bicycle       `json:"bicycles"`
+	Rows     []ComparisonRow `json:"rows"`
+}
+
+// ComparisonRow is one compared field. Values are nil for bicycles without the field.
+type ComparisonRow struct {
+	Key     string        `json:"key"` // e.g. "specifications.weight" or "attributes.battery_capacity"
+	Label   string        `json:"label"`
+	Group   string        `json:"group"`
+	Unit    string        `json:"unit,omitempty"`
+	Values  []interface{} `json:"values"`
+	Differs bool          `json:"differs"` // not every bicycle has the same value
+}
+
+// CompareBicycles builds the comparison rows of bicycles, with category attributes
+// labelled from the given categories. Prices should already have sales applied.
+func CompareBicycles(bicycles []Bicycle, categories map[primitive.ObjectID]Category) Comparison {
+	comparison := Comparison{Bicycles: bicycles}
+	add := func(key, label, group, unit string, value func(b *Bicycle) interface{}) {
+		row := ComparisonRow{Key: key, Label: label, Group: group, Unit: unit}
+		for i := range bicycles {
+			row.Values = append(row.Values, value(&bicycles[i]))
+		}
+		row.Differs = valuesDiffer(row.Values)
+		comparison.Rows = append(comparison.Rows, row)
+	}
+
+	add("brand", "Brand", ComparisonGroupOverview, "", func(b *Bicycle) interface{} { return b.Brand })
+	add("category", "Category", ComparisonGroupOverview, "", func(b *Bicycle) interface{} {
+		if category, ok := categories[b.CategoryID]; ok {
+			return category.Name
+		}
+		return nil
+	})
+	add("price", "Price", ComparisonGroupOverview, "", func(b *Bicycle) interface{} { return b.Price })
+	add("rating_avg", "Rating", ComparisonGroupOverview, "", func(b *Bicycle) interface{} {
+		if b.RatingCount == 0 {
+			return nil
+		}
+		return b.RatingAvg
+	})
+	add("rating_count", "Reviews", ComparisonGroupOverview, "", func(b *Bicycle) interface{} { return b.RatingCount })
+	add("price_per_kg", "Price per kg", ComparisonGroupOverview, "", func(b *Bicycle) interface{} {
+		if b.Specifications.Weight.Value <= 0 {
+			return nil
+		}
+		return math.Round(b.Price/b.Specifications.Weight.Value*100) / 100
+	})
+	add("in_stock", "In stock", ComparisonGroupOverview, "", func(b *Bicycle) interface{} { return b.StockQuantity > 0 })
+
+	add("specifications.frame_material", "Frame material", ComparisonGroupSpecifications, "", func(b *Bicycle) interface{} {
+		return optionalString(b.Specifications.FrameMaterial)
+	})
+	add("specifications.wheel_size", "Wheel size", ComparisonGroupSpecifications, UnitInch, func(b *Bicycle) interface{} {
+		return measurementValue(b.Specifications.WheelSize)
+	})
+	add("specifications.gear_count", "Gears", ComparisonGroupSpecifications, "", func(b *Bicycle) interface{} {
+		if b.Specifications.GearCount == 0 {
+			return nil
+		}
+		return b.Specifications.GearCount
+	})
+	add("specifications.brake_type", "Brakes", ComparisonGroupSpecifications, "", func(b *Bicycle) interface{} {
+		return optionalString(b.Specifications.BrakeType)
+	})
+	add("specifications.suspension", "Suspension", ComparisonGroupSpecifications, "", func(b *Bicycle) interface{} {
+		return optionalString(b.Specifications.Suspension)
+	})
+	add("specifications.weight", "Weight", ComparisonGroupSpecifications, UnitKilogram, func(b *Bicycle) interface{} {
+		return measurementValue(b.Specifications.Weight)
+	})
+	add("specifications.max_load", "Max load", ComparisonGroupSpecifications, UnitKilogram, func(b *Bicycle) interface{} {
+		return measurementValue(b.Specifications.MaxLoad)
+	})
+
+	for _, definition := range comparedAttributes(bicycles, categories) {
+		name := definition.Name
+		add("attributes."+name, definition.Label, ComparisonGroupAttributes, definition.Unit, func(b *Bicycle) interface{} {
+			for _, attribute := range b.Attributes {
+				if attribute.Name == name {
+					return attribute.Value
+				}
+			}
+			return nil
+		})
+	}
+
+	return comparison
+}
+
+// comparedAttributes returns the attributes of any compared bicycle, in the order of
+// each category's schema, followed by attributes no schema defines any more, by name
+func comparedAttributes(bicycles []Bicycle, categories map[primitive.ObjectID]Category) []AttributeDefinition {
+	var definitions []AttributeDefinition
+	seen := make(map[string]bool)
+	for _, bicycle := range bicycles {
+		for _, definition := range categories[bicycle.CategoryID].Attributes {
+			if !seen[definition.Name] {
+				seen[definition.Name] = true
+				definitions = append(definitions, definition)
+			}
+		}
+	}
+
+	var undefined []AttributeDefinition
+	for _, bicycle := range bicycles {
+		for _, attribute := range bicycle.Attributes {
+			if !seen[attribute.Name] {
+				seen[attribute.Name] = true
+				undefined = append(undefined, AttributeDefinition{Name: attribute.Name, Label: attribute.Name, Unit: attribute.Unit})
+			}
+		}
+	}
+	sort.Slice(undefined, func(i, j int) bool { return undefined[i].Name < undefined[j].Name })
+
+	return append(definitions, undefined...)
+}
+
+// valuesDiffer reports whether the values are not all the same. Values are compared by
+// their text, so numbers decoded as different Go types still match.
+func valuesDiffer(values []interface{}) bool {
+	for _, value := range values[1:] {
+		if fmt.Sprint(value) != fmt.Sprint(values[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// measurementValue returns the trade label of a measurement if it has one, else its value
+func measurementValue(m Measurement) interface{} {
+	if m.IsZero() {
+		return nil
+	}
+	if m.Label != "" {
+		return m.Label
+	}
+	return m.Value
+}
+
+func optionalString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -19,9 +19,11 @@ type Customer struct {
 	Name           string             `bson:"name" json:"name" binding:"required"`
 	Email          string             `bson:"email" json:"email" binding:"required,email"`
 	Password       string             `bson:"password" json:"-"`
+	OIDCSubject    string             `bson:"oidc_subject,omitempty" json:"-"` // subject claim of a linked identity provider account
 	Phone          string             `bson:"phone" json:"phone"`
 	Role           string             `bson:"role" json:"role"` // "admin" or "customer"
 	Addresses      []Address          `bson:"addresses" json:"addresses"`
+	Wishlist       []WishlistItem     `bson:"wishlist,omitempty" json:"-"` // served by /customers/me/wishlist
 	LoyaltyPoints  int                `bson:"loyalty_points" json:"loyalty_points"`
 	RegisteredDate time.Time          `bson:"registered_date" json:"registered_date"`
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
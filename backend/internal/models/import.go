@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Catalog import and export formats
+const (
+	CatalogFormatCSV    = "csv"
+	CatalogFormatNDJSON = "ndjson"
+)
+
+// Import job statuses
+const (
+	ImportStatusQueued    = "queued"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// MaxImportRowErrors bounds the row errors kept on an import job
+const MaxImportRowErrors = 500
+
+// CatalogRow is a bicycle in an import or export. An imported row updates the bicycle
+// with its ID, or else with its SKU, or else with its model name and brand, changing
+// only the fields it sets; rows matching no bicycle create one.
+type CatalogRow struct {
+	ID                   string                `json:"id,omitempty"`
+	SKU                  string                `json:"sku,omitempty"`
+	StockQuantity        *int                  `json:"stock_quantity,omitempty"` // of a bicycle without variants
+	CustomizationOptions []CustomizationOption `json:"customization_options,omitempty"`
+	Variants             []CatalogVariant      `json:"variants,omitempty"` // replace the bicycle's variants when set
+	BicycleChanges
+}
+
+// CatalogVariant is a variant in an import or export. A variant imported without stock
+// keeps the stock it has.
+type CatalogVariant struct {
+	SKU           string                  `json:"sku"`
+	Options       []SelectedCustomization `json:"options"`
+	Price         *float64                `json:"price,omitempty"`
+	StockQuantity *int                    `json:"stock_quantity,omitempty"`
+	Barcode       string                  `json:"barcode,omitempty"`
+}
+
+// ImportRowError is a row that could not be imported; rows are numbered from 1,
+// not counting the CSV header
+type ImportRowError struct {
+	Row   int    `bson:"row" json:"row"`
+	SKU   string `bson:"sku,omitempty" json:"sku,omitempty"`
+	Error string `bson:"error" json:"error"`
+}
+
+// ImportJob tracks a catalog import running in the background. A dry run validates
+// every row and counts what would be created and updated without writing.
+type ImportJob struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Format     string             `bson:"format" json:"format"`
+	FileName   string             `bson:"file_name" json:"file_name"`
+	DryRun     bool               `bson:"dry_run" json:"dry_run"`
+	Status     string             `bson:"status" json:"status"`
+	TotalRows  int                `bson:"total_rows" json:"total_rows"`
+	Processed  int                `bson:"processed" json:"processed"`
+	Created    int                `bson:"created" json:"created"`
+	Updated    int                `bson:"updated" json:"updated"`
+	Failed     int                `bson:"failed" json:"failed"`
+	RowErrors  []ImportRowError   `bson:"row_errors" json:"row_errors"`           // the first MaxImportRowErrors failed rows
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"` // why a failed job stopped
+	CreatedBy  primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	StartedAt  *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
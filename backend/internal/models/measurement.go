@@ -0,0 +1,157 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Canonical units numeric specifications are stored in, so they can be filtered and sorted
+const (
+	UnitKilogram = "kg"
+	UnitInch     = "in"
+)
+
+// Measurement is a numeric specification with its unit. Label keeps a trade
+// designation that doesn't read as a plain number, e.g. "700C" wheels.
+type Measurement struct {
+	Value float64 `bson:"value" json:"value"`
+	Unit  string  `bson:"unit" json:"unit"`
+	Label string  `bson:"label,omitempty" json:"label,omitempty"`
+}
+
+// IsZero reports whether the measurement was left unspecified
+func (m Measurement) IsZero() bool {
+	return m.Value == 0 && m.Unit == "" && m.Label == ""
+}
+
+// UnmarshalJSON also accepts the free text form, e.g. "13.5 kg", used by older clients
+func (m *Measurement) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		parsed, err := ParseMeasurement(text)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	type plain Measurement
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+var measurementPattern = regexp.MustCompile(`^\s*(\d+(?:[.,]\d+)?)\s*([^\d\s]*)\s*$`)
+
+// ParseMeasurement splits free text like "13.5 kg" or "700C" into value and unit
+// without converting it; Normalize* converts to the canonical unit
+func ParseMeasurement(text string) (Measurement, error) {
+	if strings.TrimSpace(text) == "" {
+		return Measurement{}, nil
+	}
+
+	match := measurementPattern.FindStringSubmatch(text)
+	if match == nil {
+		return Measurement{}, errors.New("invalid measurement: " + text)
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil {
+		return Measurement{}, errors.New("invalid measurement: " + text)
+	}
+
+	return Measurement{Value: value, Unit: match[2]}, nil
+}
+
+var massUnits = map[string]float64{
+	"":    1,
+	"kg":  1,
+	"kgs": 1,
+	"g":   0.001,
+	"lb":  0.45359237,
+	"lbs": 0.45359237,
+}
+
+var lengthUnits = map[string]float64{
+	"":       1,
+	"in":     1,
+	"inch":   1,
+	"inches": 1,
+	"\"":     1,
+	"cm":     1 / 2.54,
+	"mm":     1 / 25.4,
+}
+
+// wheelDesignations are the nominal inch sizes of French wheel designations
+var wheelDesignations = map[string]float64{
+	"700c": 28,
+	"650b": 27.5,
+	"650c": 26,
+}
+
+// NormalizeMass converts a weight or load to kilograms
+func NormalizeMass(m Measurement) (Measurement, error) {
+	if m.IsZero() {
+		return m, nil
+	}
+
+	factor, ok := massUnits[strings.ToLower(m.Unit)]
+	if !ok {
+		return Measurement{}, errors.New("unsupported mass unit: " + m.Unit)
+	}
+	if m.Value < 0 {
+		return Measurement{}, errors.New("mass must not be negative")
+	}
+
+	return Measurement{Value: round2(m.Value * factor), Unit: UnitKilogram, Label: m.Label}, nil
+}
+
+// NormalizeWheelSize converts a wheel size to inches, keeping designations like 700C as the label
+func NormalizeWheelSize(m Measurement) (Measurement, error) {
+	if m.IsZero() {
+		return m, nil
+	}
+
+	designation := strings.ToLower(strconv.FormatFloat(m.Value, 'f', -1, 64) + m.Unit)
+	if inches, ok := wheelDesignations[designation]; ok {
+		return Measurement{Value: inches, Unit: UnitInch, Label: strings.ToUpper(designation)}, nil
+	}
+	if inches, ok := wheelDesignations[strings.ToLower(m.Label)]; ok && m.Value == 0 {
+		return Measurement{Value: inches, Unit: UnitInch, Label: strings.ToUpper(m.Label)}, nil
+	}
+
+	factor, ok := lengthUnits[strings.ToLower(m.Unit)]
+	if !ok {
+		return Measurement{}, errors.New("unsupported wheel size unit: " + m.Unit)
+	}
+	if m.Value <= 0 {
+		return Measurement{}, errors.New("wheel size must be positive")
+	}
+
+	return Measurement{Value: round2(m.Value * factor), Unit: UnitInch, Label: m.Label}, nil
+}
+
+// Normalize converts every numeric specification to its canonical unit
+func (s *Specifications) Normalize() error {
+	var err error
+	if s.Weight, err = NormalizeMass(s.Weight); err != nil {
+		return errors.New("weight: " + err.Error())
+	}
+	if s.MaxLoad, err = NormalizeMass(s.MaxLoad); err != nil {
+		return errors.New("max_load: " + err.Error())
+	}
+	if s.WheelSize, err = NormalizeWheelSize(s.WheelSize); err != nil {
+		return errors.New("wheel_size: " + err.Error())
+	}
+	if s.GearCount < 0 {
+		return errors.New("gear_count must not be negative")
+	}
+	return nil
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// OIDCState holds what we need to finish an OpenID Connect login between the
+// redirect to the identity provider and the callback
+type OIDCState struct {
+	State        string    `bson:"_id"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"code_verifier"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+}
@@ -7,8 +7,9 @@ import (
 )
 
 type SelectedCustomization struct {
-	Name  string `bson:"name" json:"name"`   // e.g., "frame_color"
-	Value string `bson:"value" json:"value"` // e.g., "Red"
+	Name      string  `bson:"name" json:"name"`                               // e.g., "frame_color"
+	Value     string  `bson:"value" json:"value"`                             // e.g., "Red"
+	Surcharge float64 `bson:"surcharge,omitempty" json:"surcharge,omitempty"` // price added at purchase, set by the server
 }
 
 type OrderItem struct {
@@ -18,6 +19,8 @@ type OrderItem struct {
 	Quantity               int                     `bson:"quantity" json:"quantity"`
 	PriceAtPurchase        float64                 `bson:"price_at_purchase" json:"price_at_purchase"`
 	SelectedCustomizations []SelectedCustomization `bson:"selected_customizations" json:"selected_customizations"`
+	VariantID              *primitive.ObjectID     `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	SKU                    string                  `bson:"sku,omitempty" json:"sku,omitempty"`
 }
 
 type DeliveryAddress struct {
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PriceChange records a change of a bicycle's regular price and who made it.
+// The first entry of a bicycle is its price when it was created.
+type PriceChange struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BicycleID     primitive.ObjectID `bson:"bicycle_id" json:"bicycle_id"`
+	PreviousPrice float64            `bson:"previous_price" json:"previous_price"` // 0 for the initial price
+	Price         float64            `bson:"price" json:"price"`
+	ChangedBy     primitive.ObjectID `bson:"changed_by" json:"changed_by"`
+	ChangedAt     time.Time          `bson:"changed_at" json:"changed_at"`
+}
+
+// PriceHistory is a bicycle's regular price changes, newest first, and its sales
+type PriceHistory struct {
+	Changes []PriceChange `json:"changes"`
+	Sales   []SalePrice   `json:"sales"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RelatedBicycleCount bounds the similar and bought-together bicycles kept per bicycle
+const RelatedBicycleCount = 8
+
+// SimilarPriceRange is how far, as a share of a bicycle's price, a similar bicycle's price may be
+const SimilarPriceRange = 0.25
+
+// Recommendation holds the precomputed related bicycles of a bicycle
+type Recommendation struct {
+	BicycleID      primitive.ObjectID `bson:"_id" json:"bicycle_id"`
+	Similar        []SimilarBicycle   `bson:"similar" json:"similar"`
+	BoughtTogether []CoPurchase       `bson:"bought_together" json:"bought_together"`
+	ComputedAt     time.Time          `bson:"computed_at" json:"computed_at"`
+}
+
+// SimilarBicycle is a bicycle in the same category at a close price. Score counts the
+// shared specifications plus up to 1 for price closeness.
+type SimilarBicycle struct {
+	BicycleID primitive.ObjectID `bson:"bicycle_id" json:"bicycle_id"`
+	Score     float64            `bson:"score" json:"score"`
+}
+
+// CoPurchase is a bicycle ordered together with another, with the number of such orders
+type CoPurchase struct {
+	BicycleID primitive.ObjectID `bson:"bicycle_id" json:"bicycle_id"`
+	Count     int                `bson:"count" json:"count"`
+}
+
+// RelatedBicycles are the public bicycles related to a bicycle, best match first
+type RelatedBicycles struct {
+	Similar        []Bicycle `json:"similar"`
+	BoughtTogether []Bicycle `json:"bought_together"`
+}
@@ -16,6 +16,8 @@ type PaginatedResponse struct {
 	Limit      int         `json:"limit"`
 	Total      int64       `json:"total"`
 	TotalPages int64       `json:"total_pages"`
+	// Suggestions are "did you mean" alternatives for a search with no results
+	Suggestions []string `json:"suggestions,omitempty"`
 }
 
 type AuthResponse struct {
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Review moderation statuses. New and edited reviews wait in the moderation
+// queue; only approved reviews are shown publicly. Approved reviews that collect
+// enough abuse reports are flagged and hidden until a moderator decides again.
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+	ReviewStatusFlagged  = "flagged"
+)
+
+// Kinds of review votes; a customer can cast one of each per review
+const (
+	ReviewVoteHelpful = "helpful"
+	ReviewVoteReport  = "report"
+)
+
+// Review sort orders for the reviews of a bicycle
+const (
+	ReviewSortNewest  = "newest"
+	ReviewSortHighest = "highest"
+	ReviewSortHelpful = "helpful"
+)
+
+// TopReviewCount is how many of its most helpful reviews a bicycle keeps in top_reviews
+const TopReviewCount = 3
+
+// Review is stored in the reviews collection, one per customer and bicycle
+type Review struct {
+	ReviewID     primitive.ObjectID `bson:"_id" json:"review_id"`
+	BicycleID    primitive.ObjectID `bson:"bicycle_id" json:"bicycle_id"`
+	CustomerID   primitive.ObjectID `bson:"customer_id" json:"customer_id"`
+	CustomerName string             `bson:"customer_name" json:"customer_name"`
+	Rating       int                `bson:"rating" json:"rating"`
+	Comment      string             `bson:"comment" json:"comment"`
+	ReviewDate   time.Time          `bson:"review_date" json:"review_date"`
+	// VerifiedPurchase is set when the customer had a delivered order of the bicycle when writing the review
+	VerifiedPurchase bool                `bson:"verified_purchase" json:"verified_purchase"`
+	HelpfulCount     int                 `bson:"helpful_count" json:"helpful_count"`
+	ReportCount      int                 `bson:"report_count" json:"report_count"` // reports since the last moderation
+	Status           string              `bson:"status" json:"status"`
+	ModeratedBy      *primitive.ObjectID `bson:"moderated_by,omitempty" json:"moderated_by,omitempty"`
+	ModeratedAt      *time.Time          `bson:"moderated_at,omitempty" json:"moderated_at,omitempty"`
+}
+
+// IsApproved reports whether the review is shown publicly
+func (r *Review) IsApproved() bool {
+	return r.Status == ReviewStatusApproved
+}
+
+type ReviewInput struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"required"`
+}
+
+// ReviewVote is a customer's helpful vote or abuse report on a review
+type ReviewVote struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReviewID   primitive.ObjectID `bson:"review_id" json:"review_id"`
+	CustomerID primitive.ObjectID `bson:"customer_id" json:"customer_id"`
+	Kind       string             `bson:"kind" json:"kind"`
+	Reason     string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type ReviewReportInput struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+type ReviewModerationInput struct {
+	Status string `json:"status" binding:"required,oneof=approved rejected"`
+}
+
+// ReviewFilter selects the approved reviews of a bicycle
+type ReviewFilter struct {
+	Rating int    `form:"rating" binding:"omitempty,min=1,max=5"` // only reviews with this many stars
+	Sort   string `form:"sort,default=helpful"`                   // helpful, newest or highest
+	Page   int    `form:"page,default=1"`
+	Limit  int    `form:"limit,default=10"`
+}
+
+// ReviewQueueFilter selects reviews for the moderation queue, oldest first
+type ReviewQueueFilter struct {
+	Status string `form:"status,default=pending"`
+	Page   int    `form:"page,default=1"`
+	Limit  int    `form:"limit,default=20"`
+}
+
+// ReviewQueueItem is a review in the moderation queue with the bicycle it is about
+type ReviewQueueItem struct {
+	BicycleID primitive.ObjectID `bson:"bicycle_id" json:"bicycle_id"`
+	ModelName string             `bson:"model_name" json:"model_name"`
+	Brand     string             `bson:"brand" json:"brand"`
+	Review    Review             `bson:"review" json:"review"`
+}
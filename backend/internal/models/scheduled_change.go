@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scheduled change statuses. The scheduler claims a pending change by moving
+// it to applying, so a change is applied once even with several API instances.
+const (
+	ChangeStatusPending   = "pending"
+	ChangeStatusApplying  = "applying"
+	ChangeStatusApplied   = "applied"
+	ChangeStatusFailed    = "failed"
+	ChangeStatusCancelled = "cancelled"
+)
+
+// ChangeClaimTimeout is how long a change may stay applying before the scheduler takes
+// it as abandoned, e.g. by a crashed instance, and claims it again
+const ChangeClaimTimeout = 5 * time.Minute
+
+// BicycleChanges is a partial bicycle update; only the fields that are set are
+// changed. Setting status to active publishes a draft.
+type BicycleChanges struct {
+	ModelName      *string                `bson:"model_name,omitempty" json:"model_name,omitempty"`
+	Brand          *string                `bson:"brand,omitempty" json:"brand,omitempty"`
+	Price          *float64               `bson:"price,omitempty" json:"price,omitempty"`
+	CategoryID     *string                `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	Specifications *Specifications        `bson:"specifications,omitempty" json:"specifications,omitempty"`
+	Attributes     map[string]interface{} `bson:"attributes,omitempty" json:"attributes,omitempty"`
+	Description    *string                `bson:"description,omitempty" json:"description,omitempty"`
+	ImageURL       *string                `bson:"image_url,omitempty" json:"image_url,omitempty"`
+	Status         *string                `bson:"status,omitempty" json:"status,omitempty"`
+}
+
+// IsEmpty reports whether the change set changes nothing
+func (c BicycleChanges) IsEmpty() bool {
+	return c.ModelName == nil && c.Brand == nil && c.Price == nil && c.CategoryID == nil &&
+		c.Specifications == nil && c.Attributes == nil && c.Description == nil &&
+		c.ImageURL == nil && c.Status == nil
+}
+
+// ScheduledChange is a change set that goes live at EffectiveAt
+type ScheduledChange struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BicycleID   primitive.ObjectID `bson:"bicycle_id" json:"bicycle_id"`
+	Changes     BicycleChanges     `bson:"changes" json:"changes"`
+	EffectiveAt time.Time          `bson:"effective_at" json:"effective_at"`
+	Status      string             `bson:"status" json:"status"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"` // why applying failed
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"created_by"`
+	AppliedAt   *time.Time         `bson:"applied_at,omitempty" json:"applied_at,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type ScheduledChangeInput struct {
+	Changes     BicycleChanges `json:"changes"`
+	EffectiveAt time.Time      `json:"effective_at" binding:"required"`
+}
+
+type ScheduledChangeFilter struct {
+	BicycleID string `form:"bicycle_id"`
+	Status    string `form:"status"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxWishlistItems bounds the wishlist embedded in a customer document
+const MaxWishlistItems = 100
+
+type WishlistItem struct {
+	BicycleID primitive.ObjectID `bson:"bicycle_id" json:"bicycle_id"`
+	AddedAt   time.Time          `bson:"added_at" json:"added_at"`
+}
+
+type WishlistInput struct {
+	BicycleID string `json:"bicycle_id" binding:"required"`
+}
+
+// WishlistEntry is a wishlist item with its bicycle as shoppers see it
+type WishlistEntry struct {
+	Bicycle    Bicycle   `json:"bicycle"`
+	AddedAt    time.Time `json:"added_at"`
+	StockAlert bool      `json:"stock_alert"` // the customer is notified when the bicycle is back in stock
+}
+
+// Stock alert statuses. Waiting alerts are queued for notification, and marked
+// notified, when their bicycle is back in stock.
+const (
+	StockAlertWaiting  = "waiting"
+	StockAlertNotified = "notified"
+)
+
+// StockAlert is a customer's "notify me" subscription on an out-of-stock bicycle, or on
+// one sold-out variant of a bicycle with variants
+type StockAlert struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BicycleID  primitive.ObjectID `bson:"bicycle_id" json:"bicycle_id"`
+	SKU        string             `bson:"sku,omitempty" json:"sku,omitempty"` // of the variant
+	CustomerID primitive.ObjectID `bson:"customer_id" json:"customer_id"`
+	Status     string             `bson:"status" json:"status"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	NotifiedAt *time.Time         `bson:"notified_at,omitempty" json:"notified_at,omitempty"`
+}
+
+// NotificationBackInStock is the kind of notification queued by stock alerts
+const NotificationBackInStock = "back_in_stock"
+
+// Notification is queued for delivery to a customer; a mailer sends pending
+// notifications and sets sent_at
+type Notification struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CustomerID primitive.ObjectID `bson:"customer_id" json:"customer_id"`
+	Kind       string             `bson:"kind" json:"kind"`
+	BicycleID  primitive.ObjectID `bson:"bicycle_id" json:"bicycle_id"`
+	SKU        string             `bson:"sku,omitempty" json:"sku,omitempty"` // of the variant back in stock
+	Subject    string             `bson:"subject" json:"subject"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	SentAt     *time.Time         `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"bicycle-store/internal/database"
+	"bicycle-store/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type APIKeyRepository struct{}
+
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{}
+}
+
+func (r *APIKeyRepository) GetAll(ctx context.Context) ([]models.APIKey, error) {
+	collection := database.GetCollection("api_keys")
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []models.APIKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetActiveByHash finds a key that has not been revoked by the hash of its plaintext value
+func (r *APIKeyRepository) GetActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	collection := database.GetCollection("api_keys")
+
+	var key models.APIKey
+	err := collection.FindOne(ctx, bson.M{
+		"key_hash":   keyHash,
+		"revoked_at": bson.M{"$exists": false},
+	}).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	collection := database.GetCollection("api_keys")
+
+	key.CreatedAt = time.Now()
+	key.UpdatedAt = time.Now()
+
+	result, err := collection.InsertOne(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	key.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Revoke uses $set to mark a key as revoked. Revoked keys are kept for auditing.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	collection := database.GetCollection("api_keys")
+
+	update := bson.M{
+		"$set": bson.M{
+			"revoked_at": time.Now(),
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// TouchLastUsed records key usage. Writes are throttled to once a minute per key
+// so that busy integrations don't turn every request into a write.
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID) error {
+	collection := database.GetCollection("api_keys")
+
+	now := time.Now()
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+			"$or": []bson.M{
+				{"last_used_at": bson.M{"$exists": false}},
+				{"last_used_at": bson.M{"$lt": now.Add(-time.Minute)}},
+			},
+		},
+		bson.M{"$set": bson.M{"last_used_at": now}},
+	)
+	return err
+}
@@ -4,10 +4,15 @@ import (
 	"bicycle-store/internal/database"
 	"bicycle-store/internal/models"
 	"context"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -20,70 +25,424 @@ func NewBicycleRepository() *BicycleRepository {
 func (r *BicycleRepository) GetAll(ctx context.Context, filter models.BicycleFilter) ([]models.Bicycle, int64, error) {
 	collection := database.GetCollection("bicycles")
 
-	// Build filter query
+	query := buildBicycleQuery(filter)
+
+	// Count total documents
+	total, err := collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Build options for pagination and sorting
+	skip := (filter.Page - 1) * filter.Limit
+	sortOrder := -1
+	if filter.Order == "asc" {
+		sortOrder = 1
+	}
+
+	sortField := filter.Sort
+	if field, ok := sortFields[filter.Sort]; ok {
+		sortField = field
+	}
+
+	pipeline := []bson.M{{"$match": query}}
+
+	// Shoppers see running sales, so they sort by the price they would pay
+	if !filter.IncludeHidden {
+		pipeline = append(pipeline, bson.M{"$addFields": bson.M{"effective_price": effectivePrice(time.Now())}})
+		if filter.Sort == "price" {
+			sortField = "effective_price"
+		}
+	}
+
+	sortBy := bson.D{{Key: sortField, Value: sortOrder}}
+
+	// Rating ties are broken by the number of reviews
+	if filter.Sort == "rating" {
+		sortBy = bson.D{{Key: "rating_avg", Value: sortOrder}, {Key: "rating_count", Value: sortOrder}}
+	}
+
+	// Relevance sorting ranks text search matches by score, best first
+	if filter.Sort == "relevance" {
+		if filter.Search == "" {
+			sortBy = bson.D{{Key: "created_at", Value: -1}}
+		} else {
+			pipeline = append(pipeline, bson.M{"$addFields": bson.M{"score": bson.M{"$meta": "textScore"}}})
+			sortBy = bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}
+		}
+	}
+
+	// Listings leave out the top reviews; the rating aggregates summarize them
+	pipeline = append(pipeline,
+		bson.M{"$sort": sortBy},
+		bson.M{"$skip": int64(skip)},
+		bson.M{"$limit": int64(filter.Limit)},
+		bson.M{"$project": bson.M{"top_reviews": 0, "effective_price": 0}},
+	)
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var bicycles []models.Bicycle
+	if err := cursor.All(ctx, &bicycles); err != nil {
+		return nil, 0, err
+	}
+
+	return bicycles, total, nil
+}
+
+// Filter dimensions that can be excluded from buildBicycleQuery
+const (
+	facetSearch        = "search"
+	facetBrand         = "brand"
+	facetCategory      = "category"
+	facetPrice         = "price"
+	facetGears         = "gears"
+	facetBrakeType     = "brake_type"
+	facetSuspension    = "suspension"
+	facetFrameMaterial = "frame_material"
+	facetRating        = "rating"
+)
+
+// sortFields maps sort aliases to the document fields they sort on
+var sortFields = map[string]string{
+	"weight":     "specifications.weight.value",
+	"max_load":   "specifications.max_load.value",
+	"gear_count": "specifications.gear_count",
+}
+
+// buildBicycleQuery builds the filter query for a BicycleFilter, leaving out the
+// excluded dimensions. Facets exclude their own dimension so their counts respect
+// every other active filter but still show the alternatives to their own selection.
+func buildBicycleQuery(filter models.BicycleFilter, exclude ...string) bson.M {
+	excluded := make(map[string]bool)
+	for _, dimension := range exclude {
+		excluded[dimension] = true
+	}
+
 	query := bson.M{}
 
-	if filter.CategoryID != "" {
-		categoryID, err := primitive.ObjectIDFromHex(filter.CategoryID)
-		if err == nil {
+	if !filter.IncludeHidden {
+		query["status"] = publicStatus
+	} else if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	if !excluded[facetCategory] {
+		if filter.CategoryIDs != nil {
+			query["category_id"] = bson.M{"$in": filter.CategoryIDs}
+		} else if categoryID, err := primitive.ObjectIDFromHex(filter.CategoryID); err == nil {
 			query["category_id"] = categoryID
 		}
 	}
 
-	if filter.MinPrice > 0 {
-		query["price"] = bson.M{"$gte": filter.MinPrice}
+	// Shoppers filter on the price they would pay; admins on prices as stored
+	if !excluded[facetPrice] {
+		if filter.IncludeHidden {
+			addRange(query, "price", filter.MinPrice, filter.MaxPrice)
+		} else {
+			addExprRange(query, effectivePrice(time.Now()), filter.MinPrice, filter.MaxPrice)
+		}
 	}
 
-	if filter.MaxPrice > 0 {
-		if _, exists := query["price"]; exists {
-			query["price"].(bson.M)["$lte"] = filter.MaxPrice
-		} else {
-			query["price"] = bson.M{"$lte": filter.MaxPrice}
+	addRange(query, "specifications.weight.value", filter.MinWeight, filter.MaxWeight)
+	addRange(query, "specifications.max_load.value", filter.MinLoad, filter.MaxLoad)
+	if !excluded[facetRating] {
+		addRange(query, "rating_avg", filter.MinRating, 0)
+	}
+	if !excluded[facetGears] {
+		addRange(query, "specifications.gear_count", float64(filter.MinGears), float64(filter.MaxGears))
+	}
+
+	if filter.Brand != "" && !excluded[facetBrand] {
+		query["brand"] = exactMatch(filter.Brand)
+	}
+	if filter.BrakeType != "" && !excluded[facetBrakeType] {
+		query["specifications.brake_type"] = exactMatch(filter.BrakeType)
+	}
+	if filter.Suspension != "" && !excluded[facetSuspension] {
+		query["specifications.suspension"] = exactMatch(filter.Suspension)
+	}
+	if filter.FrameMaterial != "" && !excluded[facetFrameMaterial] {
+		query["specifications.frame_material"] = exactMatch(filter.FrameMaterial)
+	}
+
+	// Each category attribute condition must match one name/value pair
+	attributeConditions := []bson.M{}
+	for name, value := range filter.Attributes {
+		attributeConditions = append(attributeConditions, bson.M{
+			"attributes": bson.M{"$elemMatch": bson.M{"name": name, "value": bson.M{"$in": attributeCandidates(value)}}},
+		})
+	}
+	for name, min := range filter.AttributeMin {
+		bounds := bson.M{"$gte": min}
+		if max, ok := filter.AttributeMax[name]; ok {
+			bounds["$lte"] = max
+		}
+		attributeConditions = append(attributeConditions, bson.M{
+			"attributes": bson.M{"$elemMatch": bson.M{"name": name, "value": bounds}},
+		})
+	}
+	for name, max := range filter.AttributeMax {
+		if _, ok := filter.AttributeMin[name]; ok {
+			continue
+		}
+		attributeConditions = append(attributeConditions, bson.M{
+			"attributes": bson.M{"$elemMatch": bson.M{"name": name, "value": bson.M{"$lte": max}}},
+		})
+	}
+	if len(attributeConditions) > 0 {
+		query["$and"] = attributeConditions
+	}
+
+	// Search uses the text index on model_name, brand and description
+	if filter.Search != "" && !excluded[facetSearch] {
+		query["$text"] = bson.M{"$search": filter.Search}
+	}
+
+	return query
+}
+
+// publicStatus matches bicycles shown in the public catalog, including
+// those created before statuses existed
+var publicStatus = bson.M{"$nin": []string{models.BicycleStatusDraft, models.BicycleStatusArchived}}
+
+// addRange adds an inclusive range on field; zero bounds are ignored
+func addRange(query bson.M, field string, min, max float64) {
+	bounds := bson.M{}
+	if min > 0 {
+		bounds["$gte"] = min
+	}
+	if max > 0 {
+		bounds["$lte"] = max
+	}
+	if len(bounds) > 0 {
+		query[field] = bounds
+	}
+}
+
+// addExprRange adds an inclusive range on a computed value; zero bounds are ignored
+func addExprRange(query bson.M, value interface{}, min, max float64) {
+	bounds := bson.A{}
+	if min > 0 {
+		bounds = append(bounds, bson.M{"$gte": bson.A{value, min}})
+	}
+	if max > 0 {
+		bounds = append(bounds, bson.M{"$lte": bson.A{value, max}})
+	}
+	if len(bounds) > 0 {
+		query["$expr"] = bson.M{"$and": bounds}
+	}
+}
+
+// effectivePrice computes the price shoppers pay at now, the way Bicycle.ApplySale does:
+// the price of the first running sale, or else the regular price
+func effectivePrice(now time.Time) bson.M {
+	running := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$sales", bson.A{}}},
+		"as":    "sale",
+		"cond": bson.M{"$and": bson.A{
+			bson.M{"$lte": bson.A{"$$sale.starts_at", now}},
+			bson.M{"$gt": bson.A{"$$sale.ends_at", now}},
+		}},
+	}}
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{"running": running},
+		"in":   bson.M{"$ifNull": bson.A{bson.M{"$first": "$$running.price"}, "$price"}},
+	}}
+}
+
+// attributeCandidates returns the values a query string can mean, since
+// attribute values are stored with their schema type
+func attributeCandidates(value string) []interface{} {
+	candidates := []interface{}{value}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		candidates = append(candidates, number)
+	}
+	if boolean, err := strconv.ParseBool(value); err == nil {
+		candidates = append(candidates, boolean)
+	}
+	return candidates
+}
+
+// exactMatch matches a value case-insensitively; user input is escaped so it can't inject a pattern
+func exactMatch(value string) bson.M {
+	return bson.M{"$regex": "^" + regexp.QuoteMeta(value) + "$", "$options": "i"}
+}
+
+// searchQuery returns only the text search part of a filter
+func searchQuery(filter models.BicycleFilter) bson.M {
+	if filter.Search == "" {
+		return bson.M{}
+	}
+	return bson.M{"$text": bson.M{"$search": filter.Search}}
+}
+
+// PriceBucketBoundaries are the lower bounds of the price facet buckets
+var PriceBucketBoundaries = []float64{0, 150000, 300000, 500000, 750000, 1000000}
+
+// GetFacets uses a $facet aggregation to count bicycles per brand, category,
+// specification value, price range and rating in a single query
+func (r *BicycleRepository) GetFacets(ctx context.Context, filter models.BicycleFilter) (*models.BicycleFacets, error) {
+	collection := database.GetCollection("bicycles")
+
+	// $text is only allowed in the first stage, so search is applied once before $facet
+	valueCounts := func(exclude string, field interface{}) []bson.M {
+		return []bson.M{
+			{"$match": buildBicycleQuery(filter, facetSearch, exclude)},
+			{"$group": bson.M{"_id": field, "count": bson.M{"$sum": 1}}},
+			{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
 		}
 	}
 
-	if filter.Brand != "" {
-		query["brand"] = bson.M{"$regex": filter.Brand, "$options": "i"}
+	boundaries := make([]interface{}, 0, len(PriceBucketBoundaries)+1)
+	for _, boundary := range PriceBucketBoundaries {
+		boundaries = append(boundaries, boundary)
+	}
+	// $bucket needs an upper bound; anything above the last boundary lands in the last bucket
+	boundaries = append(boundaries, math.MaxFloat64)
+
+	pipeline := []bson.M{
+		{
+			"$match": searchQuery(filter),
+		},
+		{
+			"$facet": bson.M{
+				"brands": valueCounts(facetBrand, "$brand"),
+				// Filtering on a category includes its subcategories, so their bicycles
+				// count towards every ancestor too
+				"categories": []bson.M{
+					{"$match": buildBicycleQuery(filter, facetSearch, facetCategory)},
+					{"$group": bson.M{"_id": "$category_id", "count": bson.M{"$sum": 1}}},
+					{"$lookup": bson.M{
+						"from":         "categories",
+						"localField":   "_id",
+						"foreignField": "_id",
+						"as":           "category",
+					}},
+					{"$project": bson.M{
+						"count": 1,
+						"ids": bson.M{"$concatArrays": bson.A{
+							bson.A{"$_id"},
+							bson.M{"$ifNull": bson.A{bson.M{"$first": "$category.ancestors"}, bson.A{}}},
+						}},
+					}},
+					{"$unwind": "$ids"},
+					{"$group": bson.M{"_id": "$ids", "count": bson.M{"$sum": "$count"}}},
+					{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+					{"$lookup": bson.M{
+						"from":         "categories",
+						"localField":   "_id",
+						"foreignField": "_id",
+						"as":           "category",
+					}},
+					{"$project": bson.M{
+						"count": 1,
+						"label": bson.M{"$first": "$category.category_name"},
+					}},
+				},
+				"frame_materials": valueCounts(facetFrameMaterial, "$specifications.frame_material"),
+				"wheel_sizes":     valueCounts(facetSearch, "$specifications.wheel_size.value"),
+				"brake_types":     valueCounts(facetBrakeType, "$specifications.brake_type"),
+				"suspensions":     valueCounts(facetSuspension, "$specifications.suspension"),
+				"gear_counts":     valueCounts(facetGears, "$specifications.gear_count"),
+				"price_ranges": []bson.M{
+					{"$match": buildBicycleQuery(filter, facetSearch, facetPrice)},
+					{"$bucket": bson.M{
+						"groupBy":    facetPriceField(filter),
+						"boundaries": boundaries,
+						"output":     bson.M{"count": bson.M{"$sum": 1}},
+					}},
+				},
+				"ratings": valueCounts(facetRating, bson.M{"$cond": bson.A{
+					bson.M{"$gt": bson.A{"$rating_count", 0}},
+					bson.M{"$floor": "$rating_avg"},
+					nil,
+				}}),
+			},
+		},
 	}
 
-	if filter.Search != "" {
-		query["$or"] = []bson.M{
-			{"model_name": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"brand": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"description": bson.M{"$regex": filter.Search, "$options": "i"}},
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []models.BicycleFacets
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	facets := &results[0]
+	for i := range facets.PriceRanges {
+		bucket := &facets.PriceRanges[i]
+		// The upper bound of a bucket is the next boundary; the last bucket is open-ended
+		for j, boundary := range PriceBucketBoundaries {
+			if bucket.Min == boundary && j+1 < len(PriceBucketBoundaries) {
+				bucket.Max = PriceBucketBoundaries[j+1]
+			}
 		}
 	}
 
-	// Count total documents
-	total, err := collection.CountDocuments(ctx, query)
+	return facets, nil
+}
+
+// facetPriceField is the price the price facet buckets bicycles by, matching the price filter
+func facetPriceField(filter models.BicycleFilter) interface{} {
+	if filter.IncludeHidden {
+		return "$price"
+	}
+	return effectivePrice(time.Now())
+}
+
+// GetVocabulary returns the distinct brands and model names in the catalog
+func (r *BicycleRepository) GetVocabulary(ctx context.Context) ([]string, []string, error) {
+	collection := database.GetCollection("bicycles")
+
+	brands, err := collection.Distinct(ctx, "brand", bson.M{"status": publicStatus})
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
-	// Build options for pagination and sorting
-	skip := (filter.Page - 1) * filter.Limit
-	sortOrder := -1
-	if filter.Order == "asc" {
-		sortOrder = 1
+	modelNames, err := collection.Distinct(ctx, "model_name", bson.M{"status": publicStatus})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	opts := options.Find().
-		SetSkip(int64(skip)).
-		SetLimit(int64(filter.Limit)).
-		SetSort(bson.D{{Key: filter.Sort, Value: sortOrder}})
+	return toStrings(brands), toStrings(modelNames), nil
+}
 
-	cursor, err := collection.Find(ctx, query, opts)
+// GetSearchTerms returns the ID, model name and brand of every public bicycle
+func (r *BicycleRepository) GetSearchTerms(ctx context.Context) ([]models.Bicycle, error) {
+	collection := database.GetCollection("bicycles")
+
+	opts := options.Find().SetProjection(bson.M{"model_name": 1, "brand": 1})
+	cursor, err := collection.Find(ctx, bson.M{"status": publicStatus}, opts)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	var bicycles []models.Bicycle
 	if err := cursor.All(ctx, &bicycles); err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	return bicycles, total, nil
+	return bicycles, nil
+}
+
+func toStrings(values []interface{}) []string {
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		if str, ok := value.(string); ok && str != "" {
+			strs = append(strs, str)
+		}
+	}
+	return strs
 }
 
 func (r *BicycleRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Bicycle, error) {
@@ -98,7 +457,118 @@ func (r *BicycleRepository) GetByID(ctx context.Context, id primitive.ObjectID)
 	return &bicycle, nil
 }
 
-func (r *BicycleRepository) Create(ctx context.Context, input models.BicycleInput) (*models.Bicycle, error) {
+// GetBySKU returns the bicycle with the given SKU
+func (r *BicycleRepository) GetBySKU(ctx context.Context, sku string) (*models.Bicycle, error) {
+	return r.findOne(ctx, bson.M{"sku": sku})
+}
+
+// SKUsTaken reports whether a bicycle other than id has the SKU sku or a variant with one
+// of variantSKUs, which the unique SKU indexes would reject
+func (r *BicycleRepository) SKUsTaken(ctx context.Context, id primitive.ObjectID, sku string, variantSKUs []string) (bool, error) {
+	collection := database.GetCollection("bicycles")
+
+	taken := bson.A{}
+	if sku != "" {
+		taken = append(taken, bson.M{"sku": sku})
+	}
+	if len(variantSKUs) > 0 {
+		taken = append(taken, bson.M{"variants.sku": bson.M{"$in": variantSKUs}})
+	}
+	if len(taken) == 0 {
+		return false, nil
+	}
+
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": bson.M{"$ne": id}, "$or": taken}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetByModel returns the bicycle with the given model name and brand
+func (r *BicycleRepository) GetByModel(ctx context.Context, modelName, brand string) (*models.Bicycle, error) {
+	return r.findOne(ctx, bson.M{"model_name": modelName, "brand": brand})
+}
+
+func (r *BicycleRepository) findOne(ctx context.Context, query bson.M) (*models.Bicycle, error) {
+	collection := database.GetCollection("bicycles")
+
+	var bicycle models.Bicycle
+	opts := options.FindOne().SetProjection(bson.M{"top_reviews": 0})
+	if err := collection.FindOne(ctx, query, opts).Decode(&bicycle); err != nil {
+		return nil, err
+	}
+
+	return &bicycle, nil
+}
+
+// GetAttributeNames returns the names of the category attributes set on bicycles that
+// aren't archived, sorted
+func (r *BicycleRepository) GetAttributeNames(ctx context.Context) ([]string, error) {
+	collection := database.GetCollection("bicycles")
+
+	values, err := collection.Distinct(ctx, "attributes.name", bson.M{"status": bson.M{"$ne": models.BicycleStatusArchived}})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		if name, ok := value.(string); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ForEachForExport calls fn with every bicycle that isn't archived, oldest first,
+// reading them from a cursor so the catalog is never held in memory
+func (r *BicycleRepository) ForEachForExport(ctx context.Context, fn func(bicycle *models.Bicycle) error) error {
+	collection := database.GetCollection("bicycles")
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"top_reviews": 0, "images": 0, "sales": 0})
+	cursor, err := collection.Find(ctx, bson.M{"status": bson.M{"$ne": models.BicycleStatusArchived}}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var bicycle models.Bicycle
+		if err := cursor.Decode(&bicycle); err != nil {
+			return err
+		}
+		if err := fn(&bicycle); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// GetByIDs returns the bicycles with the given IDs, leaving out their top reviews
+func (r *BicycleRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Bicycle, error) {
+	collection := database.GetCollection("bicycles")
+
+	opts := options.Find().SetProjection(bson.M{"top_reviews": 0})
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	bicycles := []models.Bicycle{}
+	if err := cursor.All(ctx, &bicycles); err != nil {
+		return nil, err
+	}
+
+	return bicycles, nil
+}
+
+// Create inserts a bicycle. When variants are given, the bicycle stock is the sum of their stock.
+func (r *BicycleRepository) Create(ctx context.Context, input models.BicycleInput, variants []models.Variant, attributes []models.AttributeValue) (*models.Bicycle, error) {
 	collection := database.GetCollection("bicycles")
 
 	categoryID, err := primitive.ObjectIDFromHex(input.CategoryID)
@@ -107,16 +577,22 @@ func (r *BicycleRepository) Create(ctx context.Context, input models.BicycleInpu
 	}
 
 	bicycle := models.Bicycle{
+		SKU:                  input.SKU,
 		ModelName:            input.ModelName,
 		Brand:                input.Brand,
 		Price:                input.Price,
-		StockQuantity:        input.StockQuantity,
+		StockQuantity:        stockQuantity(input, variants),
 		CategoryID:           categoryID,
 		Specifications:       input.Specifications,
+		Attributes:           attributes,
 		CustomizationOptions: input.CustomizationOptions,
 		Description:          input.Description,
 		ImageURL:             input.ImageURL,
-		Reviews:              []models.Review{},
+		Variants:             variants,
+		TopReviews:           []models.Review{},
+		RatingHistogram:      models.EmptyRatingHistogram(),
+		Sales:                input.Sales,
+		Status:               input.Status,
 		CreatedAt:            time.Now(),
 		UpdatedAt:            time.Now(),
 	}
@@ -130,7 +606,9 @@ func (r *BicycleRepository) Create(ctx context.Context, input models.BicycleInpu
 	return &bicycle, nil
 }
 
-func (r *BicycleRepository) Update(ctx context.Context, id primitive.ObjectID, input models.BicycleInput) (*models.Bicycle, error) {
+// Update replaces a bicycle's fields, keeping its SKU, status and sales when input has none.
+// When variants are given, the bicycle stock is the sum of their stock.
+func (r *BicycleRepository) Update(ctx context.Context, id primitive.ObjectID, input models.BicycleInput, variants []models.Variant, attributes []models.AttributeValue) (*models.Bicycle, error) {
 	collection := database.GetCollection("bicycles")
 
 	categoryID, err := primitive.ObjectIDFromHex(input.CategoryID)
@@ -144,15 +622,26 @@ func (r *BicycleRepository) Update(ctx context.Context, id primitive.ObjectID, i
 			"model_name":            input.ModelName,
 			"brand":                 input.Brand,
 			"price":                 input.Price,
-			"stock_quantity":        input.StockQuantity,
+			"stock_quantity":        stockQuantity(input, variants),
 			"category_id":           categoryID,
 			"specifications":        input.Specifications,
+			"attributes":            attributes,
 			"customization_options": input.CustomizationOptions,
 			"description":           input.Description,
 			"image_url":             input.ImageURL,
+			"variants":              variants,
 			"updated_at":            time.Now(),
 		},
 	}
+	if input.SKU != "" {
+		update["$set"].(bson.M)["sku"] = input.SKU
+	}
+	if input.Status != "" {
+		update["$set"].(bson.M)["status"] = input.Status
+	}
+	if input.Sales != nil {
+		update["$set"].(bson.M)["sales"] = input.Sales
+	}
 
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	var bicycle models.Bicycle
@@ -164,29 +653,147 @@ func (r *BicycleRepository) Update(ctx context.Context, id primitive.ObjectID, i
 	return &bicycle, nil
 }
 
-func (r *BicycleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+// UpdateFields sets only the given fields of a bicycle that isn't archived, leaving the
+// rest, such as stock that orders change meanwhile, as they are in the database
+func (r *BicycleRepository) UpdateFields(ctx context.Context, id primitive.ObjectID, fields map[string]interface{}) (*models.Bicycle, error) {
+	collection := database.GetCollection("bicycles")
+
+	fields["updated_at"] = time.Now()
+	update := bson.M{"$set": fields}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var bicycle models.Bicycle
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": bson.M{"$ne": models.BicycleStatusArchived}},
+		update, opts,
+	).Decode(&bicycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bicycle, nil
+}
+
+// ReplaceVariants sets the given fields of a bicycle that isn't archived and replaces its
+// variants in a single update. Variants whose SKU is in keepStock keep the stock the
+// bicycle's variant with that SKU has at the time of the update, or else get none; the
+// stock of a bicycle with variants becomes their total.
+func (r *BicycleRepository) ReplaceVariants(ctx context.Context, id primitive.ObjectID, fields map[string]interface{}, variants []models.Variant, keepStock []string) (*models.Bicycle, error) {
 	collection := database.GetCollection("bicycles")
 
-	_, err := collection.DeleteOne(ctx, bson.M{"_id": id})
-	return err
+	if variants == nil {
+		variants = []models.Variant{}
+	}
+	if keepStock == nil {
+		keepStock = []string{}
+	}
+
+	// Values are wrapped in $literal so that strings starting with $ aren't read as field paths
+	set := bson.M{"updated_at": time.Now()}
+	for field, value := range fields {
+		set[field] = bson.M{"$literal": value}
+	}
+	currentStock := bson.M{"$first": bson.M{"$map": bson.M{
+		"input": bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$variants", bson.A{}}},
+			"as":    "current",
+			"cond":  bson.M{"$eq": bson.A{"$$current.sku", "$$variant.sku"}},
+		}},
+		"as": "current",
+		"in": "$$current.stock_quantity",
+	}}}
+	set["variants"] = bson.M{"$map": bson.M{
+		"input": bson.M{"$literal": variants},
+		"as":    "variant",
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$in": bson.A{"$$variant.sku", bson.M{"$literal": keepStock}}},
+			bson.M{"$mergeObjects": bson.A{"$$variant", bson.M{"stock_quantity": bson.M{"$ifNull": bson.A{currentStock, 0}}}}},
+			"$$variant",
+		}},
+	}}
+
+	// Without variants the bicycle keeps its own stock
+	pipeline := []bson.M{{"$set": set}}
+	if len(variants) > 0 {
+		pipeline = append(pipeline, bson.M{"$set": bson.M{"stock_quantity": bson.M{"$sum": "$variants.stock_quantity"}}})
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var bicycle models.Bicycle
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": bson.M{"$ne": models.BicycleStatusArchived}},
+		pipeline, opts,
+	).Decode(&bicycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bicycle, nil
+}
+
+func stockQuantity(input models.BicycleInput, variants []models.Variant) int {
+	if len(variants) == 0 {
+		return input.StockQuantity
+	}
+
+	total := 0
+	for _, variant := range variants {
+		total += variant.StockQuantity
+	}
+	return total
+}
+
+// Archive hides a bicycle from the catalog while keeping it for orders and reports. A
+// bicycle in open orders is left as it is and the number of those orders returned; the
+// count and the archiving share a transaction, and placing an order writes the bicycle
+// too, so an order placed meanwhile makes one of them retry.
+func (r *BicycleRepository) Archive(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return 0, err
+	}
+	defer session.EndSession(ctx)
+
+	var openOrders int64
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		var err error
+		openOrders, err = database.GetCollection("orders").CountDocuments(sessCtx, openOrdersOf(id))
+		if err != nil || openOrders > 0 {
+			return nil, err
+		}
+
+		now := time.Now()
+		result, err := database.GetCollection("bicycles").UpdateOne(sessCtx,
+			bson.M{"_id": id, "status": bson.M{"$ne": models.BicycleStatusArchived}},
+			bson.M{"$set": bson.M{
+				"status":     models.BicycleStatusArchived,
+				"deleted_at": now,
+				"updated_at": now,
+			}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, mongo.ErrNoDocuments
+		}
+		return nil, nil
+	})
+	return openOrders, err
 }
 
-// AddReview uses $push to add a review to the reviews array
-func (r *BicycleRepository) AddReview(ctx context.Context, bicycleID primitive.ObjectID, review models.Review) (*models.Bicycle, error) {
+// Restore makes an archived bicycle active again
+func (r *BicycleRepository) Restore(ctx context.Context, id primitive.ObjectID) (*models.Bicycle, error) {
 	collection := database.GetCollection("bicycles")
 
 	update := bson.M{
-		"$push": bson.M{
-			"reviews": review,
-		},
-		"$set": bson.M{
-			"updated_at": time.Now(),
-		},
+		"$set":   bson.M{"status": models.BicycleStatusActive, "updated_at": time.Now()},
+		"$unset": bson.M{"deleted_at": ""},
 	}
 
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	var bicycle models.Bicycle
-	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": bicycleID}, update, opts).Decode(&bicycle)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": models.BicycleStatusArchived}, update, opts).Decode(&bicycle)
 	if err != nil {
 		return nil, err
 	}
@@ -194,57 +801,219 @@ func (r *BicycleRepository) AddReview(ctx context.Context, bicycleID primitive.O
 	return &bicycle, nil
 }
 
-// RemoveReview uses $pull to remove a review from the reviews array
-func (r *BicycleRepository) RemoveReview(ctx context.Context, bicycleID, reviewID primitive.ObjectID) error {
+// GetArchivedBefore returns the IDs of bicycles archived before cutoff
+func (r *BicycleRepository) GetArchivedBefore(ctx context.Context, cutoff time.Time) ([]primitive.ObjectID, error) {
+	collection := database.GetCollection("bicycles")
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := collection.Find(ctx, archivedBefore(cutoff), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var bicycles []models.Bicycle
+	if err := cursor.All(ctx, &bicycles); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(bicycles))
+	for _, bicycle := range bicycles {
+		ids = append(ids, bicycle.ID)
+	}
+	return ids, nil
+}
+
+// DeleteArchived permanently removes the given bicycles if they are still archived
+// since before cutoff, so a bicycle restored meanwhile is kept
+func (r *BicycleRepository) DeleteArchived(ctx context.Context, ids []primitive.ObjectID, cutoff time.Time) (int64, error) {
+	collection := database.GetCollection("bicycles")
+
+	query := archivedBefore(cutoff)
+	query["_id"] = bson.M{"$in": ids}
+
+	result, err := collection.DeleteMany(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func archivedBefore(cutoff time.Time) bson.M {
+	return bson.M{
+		"status":     models.BicycleStatusArchived,
+		"deleted_at": bson.M{"$lt": cutoff},
+	}
+}
+
+// UpdateStock uses $inc to increment or decrement stock; returns the bicycle with its new stock
+func (r *BicycleRepository) UpdateStock(ctx context.Context, id primitive.ObjectID, quantity int) (*models.Bicycle, error) {
 	collection := database.GetCollection("bicycles")
 
 	update := bson.M{
-		"$pull": bson.M{
-			"reviews": bson.M{"review_id": reviewID},
+		"$inc": bson.M{
+			"stock_quantity": quantity,
 		},
 		"$set": bson.M{
 			"updated_at": time.Now(),
 		},
 	}
 
-	_, err := collection.UpdateOne(ctx, bson.M{"_id": bicycleID}, update)
-	return err
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var bicycle models.Bicycle
+	if err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&bicycle); err != nil {
+		return nil, err
+	}
+	return &bicycle, nil
 }
 
-// UpdateStock uses $inc to increment or decrement stock
-func (r *BicycleRepository) UpdateStock(ctx context.Context, id primitive.ObjectID, quantity int) error {
+// UpdateVariantStock uses $inc with an array filter to change the stock of one variant,
+// keeping the bicycle stock in step; returns the bicycle with its new stock
+func (r *BicycleRepository) UpdateVariantStock(ctx context.Context, id primitive.ObjectID, sku string, quantity int) (*models.Bicycle, error) {
 	collection := database.GetCollection("bicycles")
 
 	update := bson.M{
 		"$inc": bson.M{
-			"stock_quantity": quantity,
+			"stock_quantity":               quantity,
+			"variants.$[v].stock_quantity": quantity,
 		},
 		"$set": bson.M{
 			"updated_at": time.Now(),
 		},
 	}
 
-	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update)
-	return err
+	opts := options.FindOneAndUpdate().
+		SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"v.sku": sku}},
+		}).
+		SetReturnDocument(options.After)
+
+	var bicycle models.Bicycle
+	if err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "variants.sku": sku}, update, opts).Decode(&bicycle); err != nil {
+		return nil, err
+	}
+	return &bicycle, nil
 }
 
-// UpdateReview uses positional $ operator to update a specific review
-func (r *BicycleRepository) UpdateReview(ctx context.Context, bicycleID, reviewID primitive.ObjectID, rating int, comment string) error {
+// AddImage uses $push to append an image to the gallery
+func (r *BicycleRepository) AddImage(ctx context.Context, bicycleID primitive.ObjectID, image models.BicycleImage) (*models.Bicycle, error) {
+	collection := database.GetCollection("bicycles")
+
+	update := bson.M{
+		"$push": bson.M{"images": image},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var bicycle models.Bicycle
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": bicycleID}, update, opts).Decode(&bicycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bicycle, nil
+}
+
+// SetPrimaryImage uses array filters to mark one image primary and clear the flag on
+// the others, pointing image_url at it. A nil imageID clears image_url for an empty gallery.
+func (r *BicycleRepository) SetPrimaryImage(ctx context.Context, bicycleID primitive.ObjectID, imageID *primitive.ObjectID, imageURL string) (*models.Bicycle, error) {
+	collection := database.GetCollection("bicycles")
+
+	set := bson.M{
+		"image_url":  imageURL,
+		"updated_at": time.Now(),
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	query := bson.M{"_id": bicycleID}
+	if imageID != nil {
+		set["images.$[other].primary"] = false
+		set["images.$[target].primary"] = true
+		opts.SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{
+				bson.M{"other.image_id": bson.M{"$ne": *imageID}},
+				bson.M{"target.image_id": *imageID},
+			},
+		})
+		query["images.image_id"] = *imageID
+	}
+
+	var bicycle models.Bicycle
+	err := collection.FindOneAndUpdate(ctx, query, bson.M{"$set": set}, opts).Decode(&bicycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bicycle, nil
+}
+
+// UpdateImageAltText uses positional $ operator to change the alt text of one image
+func (r *BicycleRepository) UpdateImageAltText(ctx context.Context, bicycleID, imageID primitive.ObjectID, altText string) (*models.Bicycle, error) {
 	collection := database.GetCollection("bicycles")
 
 	update := bson.M{
 		"$set": bson.M{
-			"reviews.$.rating":      rating,
-			"reviews.$.comment":     comment,
-			"reviews.$.review_date": time.Now(),
-			"updated_at":            time.Now(),
+			"images.$.alt_text": altText,
+			"updated_at":        time.Now(),
 		},
 	}
 
-	_, err := collection.UpdateOne(
-		ctx,
-		bson.M{"_id": bicycleID, "reviews.review_id": reviewID},
-		update,
-	)
-	return err
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var bicycle models.Bicycle
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": bicycleID, "images.image_id": imageID}, update, opts).Decode(&bicycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bicycle, nil
+}
+
+// ReorderImages replaces the gallery with the same images in a new order. It only
+// matches while the gallery still holds exactly those images, so an upload or
+// delete in the meantime is not lost.
+func (r *BicycleRepository) ReorderImages(ctx context.Context, bicycleID primitive.ObjectID, images []models.BicycleImage) (*models.Bicycle, error) {
+	collection := database.GetCollection("bicycles")
+
+	ids := make([]primitive.ObjectID, 0, len(images))
+	for _, image := range images {
+		ids = append(ids, image.ImageID)
+	}
+	query := bson.M{
+		"_id":             bicycleID,
+		"images":          bson.M{"$size": len(images)},
+		"images.image_id": bson.M{"$all": ids},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"images":     images,
+			"updated_at": time.Now(),
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var bicycle models.Bicycle
+	err := collection.FindOneAndUpdate(ctx, query, update, opts).Decode(&bicycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bicycle, nil
+}
+
+// RemoveImage uses $pull to remove an image from the gallery
+func (r *BicycleRepository) RemoveImage(ctx context.Context, bicycleID, imageID primitive.ObjectID) (*models.Bicycle, error) {
+	collection := database.GetCollection("bicycles")
+
+	update := bson.M{
+		"$pull": bson.M{"images": bson.M{"image_id": imageID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var bicycle models.Bicycle
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": bicycleID, "images.image_id": imageID}, update, opts).Decode(&bicycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bicycle, nil
 }
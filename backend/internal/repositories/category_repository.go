@@ -8,6 +8,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -46,12 +47,73 @@ func (r *CategoryRepository) GetByID(ctx context.Context, id primitive.ObjectID)
 	return &category, nil
 }
 
-func (r *CategoryRepository) Create(ctx context.Context, input models.CategoryInput) (*models.Category, error) {
+// GetBySlug finds a category by its URL slug
+func (r *CategoryRepository) GetBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	collection := database.GetCollection("categories")
+
+	var category models.Category
+	err := collection.FindOne(ctx, bson.M{"slug": slug}).Decode(&category)
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// GetByIDs returns the given categories in no particular order
+func (r *CategoryRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Category, error) {
+	collection := database.GetCollection("categories")
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var categories []models.Category
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// GetSubtreeIDs returns the ID of a category and of all its descendants
+func (r *CategoryRepository) GetSubtreeIDs(ctx context.Context, id primitive.ObjectID) ([]primitive.ObjectID, error) {
+	collection := database.GetCollection("categories")
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := collection.Find(ctx, bson.M{"$or": []bson.M{{"_id": id}, {"ancestors": id}}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ids := []primitive.ObjectID{}
+	for cursor.Next(ctx) {
+		var category struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&category); err != nil {
+			return nil, err
+		}
+		ids = append(ids, category.ID)
+	}
+
+	return ids, cursor.Err()
+}
+
+// Create inserts a category under the last of its ancestors, or at the top level when there are none
+func (r *CategoryRepository) Create(ctx context.Context, input models.CategoryInput, slug string, ancestors []primitive.ObjectID) (*models.Category, error) {
 	collection := database.GetCollection("categories")
 
 	category := models.Category{
 		Name:        input.Name,
+		Slug:        slug,
+		ParentID:    parentOf(ancestors),
+		Ancestors:   ancestors,
 		Description: input.Description,
+		Attributes:  input.Attributes,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -65,20 +127,38 @@ func (r *CategoryRepository) Create(ctx context.Context, input models.CategoryIn
 	return &category, nil
 }
 
-func (r *CategoryRepository) Update(ctx context.Context, id primitive.ObjectID, input models.CategoryInput) (*models.Category, error) {
-	collection := database.GetCollection("categories")
-
-	update := bson.M{
-		"$set": bson.M{
-			"category_name": input.Name,
-			"description":   input.Description,
-			"updated_at":    time.Now(),
-		},
+// Update replaces a category's fields. When the category moves to another parent,
+// the ancestors of its whole subtree are rewritten in the same transaction.
+func (r *CategoryRepository) Update(ctx context.Context, id primitive.ObjectID, input models.CategoryInput, slug string, ancestors []primitive.ObjectID) (*models.Category, error) {
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return nil, err
 	}
+	defer session.EndSession(ctx)
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	var category models.Category
-	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&category)
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		collection := database.GetCollection("categories")
+
+		update := bson.M{
+			"$set": bson.M{
+				"category_name": input.Name,
+				"slug":          slug,
+				"parent_id":     parentOf(ancestors),
+				"ancestors":     ancestors,
+				"description":   input.Description,
+				"attributes":    input.Attributes,
+				"updated_at":    time.Now(),
+			},
+		}
+
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := collection.FindOneAndUpdate(sessCtx, bson.M{"_id": id}, update, opts).Decode(&category); err != nil {
+			return nil, err
+		}
+
+		return nil, rebaseDescendants(sessCtx, id, pathTo(ancestors, id))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -86,9 +166,117 @@ func (r *CategoryRepository) Update(ctx context.Context, id primitive.ObjectID,
 	return &category, nil
 }
 
-func (r *CategoryRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+// Delete removes a category. Without a reassignment target, a category that still has
+// subcategories or bicycles is left as it is and their numbers are returned. With one,
+// its subcategories and bicycles are first moved to the target, once fits accepted each
+// of the bicycles. Checks and writes share a transaction.
+func (r *CategoryRepository) Delete(ctx context.Context, id primitive.ObjectID, target *models.Category, fits func(bicycle *models.Bicycle) error) (int64, int64, error) {
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer session.EndSession(ctx)
+
+	var children, bicycles int64
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		collection := database.GetCollection("categories")
+		bicyclesCollection := database.GetCollection("bicycles")
+
+		if target == nil {
+			var err error
+			if children, err = collection.CountDocuments(sessCtx, bson.M{"parent_id": id}); err != nil {
+				return nil, err
+			}
+			if bicycles, err = bicyclesCollection.CountDocuments(sessCtx, bson.M{"category_id": id}); err != nil {
+				return nil, err
+			}
+			if children > 0 || bicycles > 0 {
+				return nil, nil
+			}
+
+			_, err = collection.DeleteOne(sessCtx, bson.M{"_id": id})
+			return nil, err
+		}
+
+		cursor, err := bicyclesCollection.Find(sessCtx, bson.M{"category_id": id},
+			options.Find().SetProjection(bson.M{"model_name": 1, "brand": 1, "attributes": 1}))
+		if err != nil {
+			return nil, err
+		}
+		var moved []models.Bicycle
+		if err := cursor.All(sessCtx, &moved); err != nil {
+			return nil, err
+		}
+		for i := range moved {
+			if err := fits(&moved[i]); err != nil {
+				return nil, err
+			}
+		}
+
+		// Children move under the target; the rest of the subtree follows via ancestors
+		_, err = collection.UpdateMany(sessCtx,
+			bson.M{"parent_id": id},
+			bson.M{"$set": bson.M{"parent_id": target.ID, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := rebaseDescendants(sessCtx, id, pathTo(target.Ancestors, target.ID)); err != nil {
+			return nil, err
+		}
+
+		_, err = bicyclesCollection.UpdateMany(sessCtx,
+			bson.M{"category_id": id},
+			bson.M{"$set": bson.M{"category_id": target.ID, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = collection.DeleteOne(sessCtx, bson.M{"_id": id})
+		return nil, err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return children, bicycles, nil
+}
+
+// rebaseDescendants replaces everything up to and including id in the ancestors
+// of id's descendants with prefix, using an update pipeline so each descendant
+// keeps the part of its path below id
+func rebaseDescendants(ctx context.Context, id primitive.ObjectID, prefix []primitive.ObjectID) error {
 	collection := database.GetCollection("categories")
 
-	_, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	_, err := collection.UpdateMany(ctx,
+		bson.M{"ancestors": id},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"ancestors": bson.M{"$concatArrays": []interface{}{
+					prefix,
+					bson.M{"$slice": []interface{}{
+						"$ancestors",
+						bson.M{"$add": []interface{}{bson.M{"$indexOfArray": []interface{}{"$ancestors", id}}, 1}},
+						bson.M{"$size": "$ancestors"},
+					}},
+				}},
+				"updated_at": time.Now(),
+			}}},
+		},
+	)
 	return err
 }
+
+// pathTo returns a new slice with id appended to ancestors
+func pathTo(ancestors []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	path := make([]primitive.ObjectID, 0, len(ancestors)+1)
+	return append(append(path, ancestors...), id)
+}
+
+func parentOf(ancestors []primitive.ObjectID) *primitive.ObjectID {
+	if len(ancestors) == 0 {
+		return nil
+	}
+	parent := ancestors[len(ancestors)-1]
+	return &parent
+}
@@ -4,6 +4,7 @@ import (
 	"bicycle-store/internal/database"
 	"bicycle-store/internal/models"
 	"context"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -58,6 +59,18 @@ func (r *CustomerRepository) GetByEmail(ctx context.Context, email string) (*mod
 	return &customer, nil
 }
 
+func (r *CustomerRepository) GetByOIDCSubject(ctx context.Context, subject string) (*models.Customer, error) {
+	collection := database.GetCollection("customers")
+
+	var customer models.Customer
+	err := collection.FindOne(ctx, bson.M{"oidc_subject": subject}).Decode(&customer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &customer, nil
+}
+
 func (r *CustomerRepository) Create(ctx context.Context, customer *models.Customer) error {
 	collection := database.GetCollection("customers")
 
@@ -155,6 +168,49 @@ func (r *CustomerRepository) RemoveAddress(ctx context.Context, customerID primi
 	return err
 }
 
+// AddToWishlist uses $push to add a bicycle to the wishlist. It matches nothing, and
+// reports false, when the bicycle is already on the wishlist or the wishlist is full.
+func (r *CustomerRepository) AddToWishlist(ctx context.Context, customerID primitive.ObjectID, item models.WishlistItem) (bool, error) {
+	collection := database.GetCollection("customers")
+
+	query := bson.M{
+		"_id":                 customerID,
+		"wishlist.bicycle_id": bson.M{"$ne": item.BicycleID},
+		fmt.Sprintf("wishlist.%d", models.MaxWishlistItems-1): bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$push": bson.M{
+			"wishlist": item,
+		},
+		"$set": bson.M{
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, query, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// RemoveFromWishlist uses $pull to remove a bicycle from the wishlist
+func (r *CustomerRepository) RemoveFromWishlist(ctx context.Context, customerID, bicycleID primitive.ObjectID) error {
+	collection := database.GetCollection("customers")
+
+	update := bson.M{
+		"$pull": bson.M{
+			"wishlist": bson.M{"bicycle_id": bicycleID},
+		},
+		"$set": bson.M{
+			"updated_at": time.Now(),
+		},
+	}
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": customerID}, update)
+	return err
+}
+
 // UpdateLoyaltyPoints uses $inc to increment loyalty points
 func (r *CustomerRepository) UpdateLoyaltyPoints(ctx context.Context, customerID primitive.ObjectID, points int) error {
 	collection := database.GetCollection("customers")
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"bicycle-store/internal/database"
+	"bicycle-store/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ImportJobRepository struct{}
+
+func NewImportJobRepository() *ImportJobRepository {
+	return &ImportJobRepository{}
+}
+
+// GetRecent returns the latest import jobs, newest first, without their row errors
+func (r *ImportJobRepository) GetRecent(ctx context.Context, limit int) ([]models.ImportJob, error) {
+	collection := database.GetCollection("import_jobs")
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"row_errors": 0})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []models.ImportJob{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (r *ImportJobRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.ImportJob, error) {
+	collection := database.GetCollection("import_jobs")
+
+	var job models.ImportJob
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Create adds a queued job
+func (r *ImportJobRepository) Create(ctx context.Context, job *models.ImportJob) error {
+	collection := database.GetCollection("import_jobs")
+
+	job.Status = models.ImportStatusQueued
+	job.CreatedAt = time.Now()
+	if job.RowErrors == nil {
+		job.RowErrors = []models.ImportRowError{}
+	}
+
+	result, err := collection.InsertOne(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	job.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Save writes the job's status, counters and row errors
+func (r *ImportJobRepository) Save(ctx context.Context, job *models.ImportJob) error {
+	collection := database.GetCollection("import_jobs")
+
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": job.ID}, job)
+	return err
+}
+
+// FailInterrupted fails the jobs left queued or running by a previous run of the API,
+// which import in-process. Returns how many jobs were failed.
+func (r *ImportJobRepository) FailInterrupted(ctx context.Context, before time.Time) (int64, error) {
+	collection := database.GetCollection("import_jobs")
+
+	now := time.Now()
+	result, err := collection.UpdateMany(ctx,
+		bson.M{
+			"status":     bson.M{"$in": []string{models.ImportStatusQueued, models.ImportStatusRunning}},
+			"created_at": bson.M{"$lt": before},
+		},
+		bson.M{"$set": bson.M{
+			"status":      models.ImportStatusFailed,
+			"error":       "interrupted by a server restart",
+			"finished_at": now,
+		}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"bicycle-store/internal/database"
+	"bicycle-store/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type OIDCStateRepository struct{}
+
+func NewOIDCStateRepository() *OIDCStateRepository {
+	return &OIDCStateRepository{}
+}
+
+func (r *OIDCStateRepository) Create(ctx context.Context, state *models.OIDCState) error {
+	collection := database.GetCollection("oidc_states")
+
+	_, err := collection.InsertOne(ctx, state)
+	return err
+}
+
+// Consume uses FindOneAndDelete so each state can only complete one login
+func (r *OIDCStateRepository) Consume(ctx context.Context, state string) (*models.OIDCState, error) {
+	collection := database.GetCollection("oidc_states")
+
+	var oidcState models.OIDCState
+	err := collection.FindOneAndDelete(ctx, bson.M{
+		"_id":        state,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&oidcState)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oidcState, nil
+}
@@ -155,17 +155,18 @@ func (r *OrderRepository) CreateWithTransaction(ctx context.Context, order *mode
 
 		// Decrement stock for each item using $inc
 		for _, item := range order.Items {
-			updateResult, err := bicyclesCollection.UpdateOne(
-				sessCtx,
-				bson.M{
-					"_id":            item.BicycleID,
+			// An archived bicycle can't be ordered, however recently it was archived
+			filter, update, opts := stockUpdate(item, -item.Quantity)
+			filter["status"] = bson.M{"$ne": models.BicycleStatusArchived}
+			filter["stock_quantity"] = bson.M{"$gte": item.Quantity}
+			if item.VariantID != nil {
+				filter["variants"] = bson.M{"$elemMatch": bson.M{
+					"variant_id":     *item.VariantID,
 					"stock_quantity": bson.M{"$gte": item.Quantity},
-				},
-				bson.M{
-					"$inc": bson.M{"stock_quantity": -item.Quantity},
-					"$set": bson.M{"updated_at": time.Now()},
-				},
-			)
+				}}
+			}
+
+			updateResult, err := bicyclesCollection.UpdateOne(sessCtx, filter, update, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -312,6 +313,71 @@ func (r *OrderRepository) UpdateItemQuantity(ctx context.Context, orderID, bicyc
 	return err
 }
 
+// openOrderStatuses are the statuses of orders that have not been delivered or cancelled yet
+var openOrderStatuses = []string{"pending", "confirmed", "shipped"}
+
+// openOrdersOf matches the open orders containing a bicycle
+func openOrdersOf(bicycleID primitive.ObjectID) bson.M {
+	return bson.M{
+		"items.bicycle_id": bicycleID,
+		"status":           bson.M{"$in": openOrderStatuses},
+	}
+}
+
+// HasDelivered reports whether a customer has a delivered order containing a bicycle
+func (r *OrderRepository) HasDelivered(ctx context.Context, customerID, bicycleID primitive.ObjectID) (bool, error) {
+	collection := database.GetCollection("orders")
+
+	count, err := collection.CountDocuments(ctx, bson.M{
+		"customer_id":      customerID,
+		"status":           "delivered",
+		"items.bicycle_id": bicycleID,
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetPurchasedBicycleIDs returns the bicycles in a customer's orders that were not cancelled
+func (r *OrderRepository) GetPurchasedBicycleIDs(ctx context.Context, customerID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	collection := database.GetCollection("orders")
+
+	values, err := collection.Distinct(ctx, "items.bicycle_id", bson.M{
+		"customer_id": customerID,
+		"status":      bson.M{"$ne": "cancelled"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bicycleIDs := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			bicycleIDs = append(bicycleIDs, id)
+		}
+	}
+	return bicycleIDs, nil
+}
+
+// GetReferencedBicycleIDs returns which of the given bicycles appear in any order
+func (r *OrderRepository) GetReferencedBicycleIDs(ctx context.Context, bicycleIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	collection := database.GetCollection("orders")
+
+	values, err := collection.Distinct(ctx, "items.bicycle_id", bson.M{"items.bicycle_id": bson.M{"$in": bicycleIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[primitive.ObjectID]bool, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			referenced[id] = true
+		}
+	}
+	return referenced, nil
+}
+
 func (r *OrderRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	collection := database.GetCollection("orders")
 
@@ -319,14 +385,16 @@ func (r *OrderRepository) Delete(ctx context.Context, id primitive.ObjectID) err
 	return err
 }
 
-// CancelOrderWithTransaction cancels an order and restores stock
-func (r *OrderRepository) CancelOrderWithTransaction(ctx context.Context, orderID primitive.ObjectID) error {
+// CancelOrderWithTransaction cancels an order and restores stock. Returns the stock of the
+// order's bicycles before and after, in the same order.
+func (r *OrderRepository) CancelOrderWithTransaction(ctx context.Context, orderID primitive.ObjectID) ([]models.Bicycle, []models.Bicycle, error) {
 	session, err := database.Client.StartSession()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer session.EndSession(ctx)
 
+	var before, after []models.Bicycle
 	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
 		ordersCollection := database.GetCollection("orders")
 		bicyclesCollection := database.GetCollection("bicycles")
@@ -338,21 +406,27 @@ func (r *OrderRepository) CancelOrderWithTransaction(ctx context.Context, orderI
 			return nil, err
 		}
 
+		bicycleIDs := make([]primitive.ObjectID, 0, len(order.Items))
+		for _, item := range order.Items {
+			bicycleIDs = append(bicycleIDs, item.BicycleID)
+		}
+		if before, err = findStock(sessCtx, bicycleIDs); err != nil {
+			return nil, err
+		}
+
 		// Restore stock for each item
 		for _, item := range order.Items {
-			_, err := bicyclesCollection.UpdateOne(
-				sessCtx,
-				bson.M{"_id": item.BicycleID},
-				bson.M{
-					"$inc": bson.M{"stock_quantity": item.Quantity},
-					"$set": bson.M{"updated_at": time.Now()},
-				},
-			)
+			filter, update, opts := stockUpdate(item, item.Quantity)
+			_, err := bicyclesCollection.UpdateOne(sessCtx, filter, update, opts)
 			if err != nil {
 				return nil, err
 			}
 		}
 
+		if after, err = findStock(sessCtx, bicycleIDs); err != nil {
+			return nil, err
+		}
+
 		// Update order status to cancelled
 		_, err = ordersCollection.UpdateOne(
 			sessCtx,
@@ -370,6 +444,47 @@ func (r *OrderRepository) CancelOrderWithTransaction(ctx context.Context, orderI
 
 		return nil, nil
 	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-	return err
+	return before, after, nil
+}
+
+// findStock reads the stock of bicycles, ordered by ID
+func findStock(ctx context.Context, ids []primitive.ObjectID) ([]models.Bicycle, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"stock_quantity": 1, "variants.sku": 1, "variants.stock_quantity": 1}).
+		SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := database.GetCollection("bicycles").Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var bicycles []models.Bicycle
+	if err := cursor.All(ctx, &bicycles); err != nil {
+		return nil, err
+	}
+	return bicycles, nil
+}
+
+// stockUpdate builds the $inc update that changes stock for an order item.
+// Items ordered as a variant also change that variant's stock via an array filter.
+func stockUpdate(item models.OrderItem, quantity int) (bson.M, bson.M, *options.UpdateOptions) {
+	filter := bson.M{"_id": item.BicycleID}
+	inc := bson.M{"stock_quantity": quantity}
+	opts := options.Update()
+
+	if item.VariantID != nil {
+		inc["variants.$[v].stock_quantity"] = quantity
+		opts.SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"v.variant_id": *item.VariantID}},
+		})
+	}
+
+	update := bson.M{
+		"$inc": inc,
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	return filter, update, opts
 }
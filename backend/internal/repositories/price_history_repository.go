@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"bicycle-store/internal/database"
+	"bicycle-store/internal/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PriceHistoryRepository struct{}
+
+func NewPriceHistoryRepository() *PriceHistoryRepository {
+	return &PriceHistoryRepository{}
+}
+
+// GetByBicycle returns the price changes of a bicycle, newest first
+func (r *PriceHistoryRepository) GetByBicycle(ctx context.Context, bicycleID primitive.ObjectID) ([]models.PriceChange, error) {
+	collection := database.GetCollection("price_history")
+
+	opts := options.Find().SetSort(bson.D{{Key: "changed_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"bicycle_id": bicycleID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	changes := []models.PriceChange{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (r *PriceHistoryRepository) Create(ctx context.Context, change *models.PriceChange) error {
+	collection := database.GetCollection("price_history")
+
+	result, err := collection.InsertOne(ctx, change)
+	if err != nil {
+		return err
+	}
+
+	change.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"bicycle-store/internal/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RateLimitRepository struct{}
+
+func NewRateLimitRepository() *RateLimitRepository {
+	return &RateLimitRepository{}
+}
+
+type rateLimitBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+// Take refills the bucket for key and takes one token from it in a single atomic
+// pipeline update, so concurrent API instances share the same bucket.
+// It returns the tokens left and whether the request is allowed.
+func (r *RateLimitRepository) Take(ctx context.Context, key string, rate float64, burst int) (float64, bool, error) {
+	collection := database.GetCollection("rate_limits")
+
+	now := time.Now()
+	// Buckets idle for longer than a full refill are equivalent to new ones and can expire
+	ttl := time.Hour
+	if rate > 0 {
+		ttl = time.Duration(float64(burst) / rate * float64(time.Second))
+	}
+
+	pipeline := []bson.M{
+		// Stage 1: Refill tokens for the time elapsed since the last request
+		{
+			"$set": bson.M{
+				"tokens": bson.M{
+					"$min": []interface{}{
+						float64(burst),
+						bson.M{
+							"$add": []interface{}{
+								bson.M{"$ifNull": []interface{}{"$tokens", float64(burst)}},
+								bson.M{"$multiply": []interface{}{
+									bson.M{"$divide": []interface{}{
+										bson.M{"$subtract": []interface{}{now, bson.M{"$ifNull": []interface{}{"$updated_at", now}}}},
+										1000,
+									}},
+									rate,
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+		// Stage 2: Decide whether a token is available
+		{
+			"$set": bson.M{
+				"allowed": bson.M{"$gte": []interface{}{"$tokens", 1}},
+			},
+		},
+		// Stage 3: Take the token
+		{
+			"$set": bson.M{
+				"tokens":     bson.M{"$cond": []interface{}{"$allowed", bson.M{"$subtract": []interface{}{"$tokens", 1}}, "$tokens"}},
+				"updated_at": now,
+				"expires_at": now.Add(ttl),
+			},
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var bucket rateLimitBucket
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&bucket)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent first request created the bucket; take from it
+		err = collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&bucket)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return bucket.Tokens, bucket.Allowed, nil
+}
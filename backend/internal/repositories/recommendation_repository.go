@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"bicycle-store/internal/database"
+	"bicycle-store/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RecommendationRepository struct{}
+
+func NewRecommendationRepository() *RecommendationRepository {
+	return &RecommendationRepository{}
+}
+
+// GetByBicycleIDs returns the recommendations of the given bicycles that have been computed
+func (r *RecommendationRepository) GetByBicycleIDs(ctx context.Context, bicycleIDs []primitive.ObjectID) ([]models.Recommendation, error) {
+	collection := database.GetCollection("recommendations")
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": bicycleIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	recommendations := []models.Recommendation{}
+	if err := cursor.All(ctx, &recommendations); err != nil {
+		return nil, err
+	}
+
+	return recommendations, nil
+}
+
+// Refresh recomputes the recommendations of every public bicycle and removes those of
+// bicycles that left the catalog. Returns how many bicycles have recommendations.
+// Pipeline: $match public bicycles -> $lookup similar bicycles -> $lookup orders
+// containing the bicycle, counting the other bicycles in them -> $merge into recommendations
+func (r *RecommendationRepository) Refresh(ctx context.Context) (int64, error) {
+	// Stamped on every document this run writes, so leftovers of earlier runs can be told apart
+	computedAt := time.Now().Truncate(time.Millisecond)
+
+	cursor, err := database.GetCollection("bicycles").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": publicStatus}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "bicycles",
+			"let": bson.M{
+				"id":         "$_id",
+				"category":   "$category_id",
+				"price":      "$price",
+				"frame":      specification("$specifications.frame_material"),
+				"brakes":     specification("$specifications.brake_type"),
+				"suspension": specification("$specifications.suspension"),
+				"gears":      specification("$specifications.gear_count"),
+				"wheel":      specification("$specifications.wheel_size.value"),
+			},
+			"pipeline": similarPipeline(),
+			"as":       "similar",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "orders",
+			"localField":   "_id",
+			"foreignField": "items.bicycle_id",
+			"let":          bson.M{"id": "$_id"},
+			"pipeline":     boughtTogetherPipeline(),
+			"as":           "bought_together",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"similar":         1,
+			"bought_together": 1,
+			"computed_at":     computedAt,
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           "recommendations",
+			"on":             "_id",
+			"whenMatched":    "replace",
+			"whenNotMatched": "insert",
+		}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	cursor.Close(ctx)
+
+	collection := database.GetCollection("recommendations")
+	if _, err := collection.DeleteMany(ctx, bson.M{"computed_at": bson.M{"$ne": computedAt}}); err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, bson.M{})
+}
+
+// similarPipeline finds public bicycles in the same category within SimilarPriceRange of
+// the price, scored by shared specifications and price closeness
+func similarPipeline() bson.A {
+	priceRange := bson.M{"$max": bson.A{bson.M{"$multiply": bson.A{"$$price", models.SimilarPriceRange}}, 1}}
+	shared := func(field, variable string) bson.M {
+		return bson.M{"$cond": bson.A{
+			bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{specification(field), variable}},
+				bson.M{"$not": bson.A{bson.M{"$in": bson.A{variable, bson.A{"", 0}}}}},
+			}},
+			1, 0,
+		}}
+	}
+
+	return bson.A{
+		bson.M{"$match": bson.M{
+			"status": publicStatus,
+			"$expr": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$category_id", "$$category"}},
+				bson.M{"$ne": bson.A{"$_id", "$$id"}},
+				bson.M{"$lte": bson.A{bson.M{"$abs": bson.M{"$subtract": bson.A{"$price", "$$price"}}}, priceRange}},
+			}},
+		}},
+		bson.M{"$project": bson.M{
+			"_id":        0,
+			"bicycle_id": "$_id",
+			"score": bson.M{"$add": bson.A{
+				shared("$specifications.frame_material", "$$frame"),
+				shared("$specifications.brake_type", "$$brakes"),
+				shared("$specifications.suspension", "$$suspension"),
+				shared("$specifications.gear_count", "$$gears"),
+				shared("$specifications.wheel_size.value", "$$wheel"),
+				bson.M{"$subtract": bson.A{1, bson.M{"$divide": bson.A{
+					bson.M{"$abs": bson.M{"$subtract": bson.A{"$price", "$$price"}}},
+					priceRange,
+				}}}},
+			}},
+		}},
+		bson.M{"$sort": bson.D{{Key: "score", Value: -1}, {Key: "bicycle_id", Value: 1}}},
+		bson.M{"$limit": models.RelatedBicycleCount},
+	}
+}
+
+// specification reads a specification field, with "" for missing ones so that they
+// aren't taken as shared
+func specification(field string) bson.M {
+	return bson.M{"$ifNull": bson.A{field, ""}}
+}
+
+// boughtTogetherPipeline counts, over the orders containing a bicycle that were not
+// cancelled, the orders containing each other bicycle
+func boughtTogetherPipeline() bson.A {
+	return bson.A{
+		bson.M{"$match": bson.M{"status": bson.M{"$ne": "cancelled"}}},
+		// An order counts once however many lines of a bicycle it has
+		bson.M{"$project": bson.M{
+			"_id": 0,
+			"others": bson.M{"$setDifference": bson.A{
+				bson.M{"$setUnion": bson.A{"$items.bicycle_id", bson.A{}}},
+				bson.A{"$$id"},
+			}},
+		}},
+		bson.M{"$unwind": "$others"},
+		bson.M{"$group": bson.M{"_id": "$others", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": models.RelatedBicycleCount},
+		bson.M{"$project": bson.M{"_id": 0, "bicycle_id": "$_id", "count": 1}},
+	}
+}
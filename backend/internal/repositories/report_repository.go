@@ -67,8 +67,9 @@ func (r *ReportRepository) GetSalesByCategory(ctx context.Context) ([]models.Sal
 		{
 			"$group": bson.M{
 				"_id": "$category_info._id",
+				// Bicycles whose category was removed are reported together
 				"category_name": bson.M{
-					"$first": "$category_info.category_name",
+					"$first": bson.M{"$ifNull": []interface{}{"$category_info.category_name", "Uncategorized"}},
 				},
 				"total_sales": bson.M{
 					"$sum": bson.M{
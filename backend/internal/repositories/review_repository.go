@@ -0,0 +1,393 @@
+package repositories
+
+import (
+	"bicycle-store/internal/database"
+	"bicycle-store/internal/models"
+	"context"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReviewRepository struct{}
+
+func NewReviewRepository() *ReviewRepository {
+	return &ReviewRepository{}
+}
+
+// reviewSorts maps the review sort orders to their sort keys, newest first on ties
+var reviewSorts = map[string]bson.D{
+	models.ReviewSortNewest:  {{Key: "review_date", Value: -1}},
+	models.ReviewSortHighest: {{Key: "rating", Value: -1}, {Key: "review_date", Value: -1}},
+	models.ReviewSortHelpful: {{Key: "helpful_count", Value: -1}, {Key: "review_date", Value: -1}},
+}
+
+// IsReviewSort reports whether sort is a known review sort order
+func IsReviewSort(sort string) bool {
+	_, ok := reviewSorts[sort]
+	return ok
+}
+
+// GetByID returns a review of a bicycle
+func (r *ReviewRepository) GetByID(ctx context.Context, bicycleID, reviewID primitive.ObjectID) (*models.Review, error) {
+	collection := database.GetCollection("reviews")
+
+	var review models.Review
+	err := collection.FindOne(ctx, bson.M{"_id": reviewID, "bicycle_id": bicycleID}).Decode(&review)
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+// GetByCustomer returns the review a customer wrote of a bicycle
+func (r *ReviewRepository) GetByCustomer(ctx context.Context, bicycleID, customerID primitive.ObjectID) (*models.Review, error) {
+	collection := database.GetCollection("reviews")
+
+	var review models.Review
+	err := collection.FindOne(ctx, bson.M{"bicycle_id": bicycleID, "customer_id": customerID}).Decode(&review)
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+// GetByBicycle returns a page of the approved reviews of a bicycle and their total
+func (r *ReviewRepository) GetByBicycle(ctx context.Context, bicycleID primitive.ObjectID, filter models.ReviewFilter) ([]models.Review, int64, error) {
+	collection := database.GetCollection("reviews")
+
+	query := bson.M{"bicycle_id": bicycleID, "status": models.ReviewStatusApproved}
+	if filter.Rating > 0 {
+		query["rating"] = filter.Rating
+	}
+
+	total, err := collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(reviewSorts[filter.Sort]).
+		SetSkip(int64((filter.Page - 1) * filter.Limit)).
+		SetLimit(int64(filter.Limit))
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	reviews := []models.Review{}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, 0, err
+	}
+
+	return reviews, total, nil
+}
+
+// Create inserts a new review. The unique bicycle_id/customer_id index rejects a second
+// review by the same customer with a duplicate key error.
+func (r *ReviewRepository) Create(ctx context.Context, review *models.Review) error {
+	return r.writeWithRatings(ctx, review.BicycleID, nil, review, func(sessCtx context.Context) error {
+		_, err := database.GetCollection("reviews").InsertOne(sessCtx, review)
+		return err
+	})
+}
+
+// Update changes the rating and comment of a review, sending it back to the
+// moderation queue and out of the rating aggregates until approved again
+func (r *ReviewRepository) Update(ctx context.Context, previous models.Review, rating int, comment string, verifiedPurchase bool) error {
+	update := bson.M{
+		"$set": bson.M{
+			"rating":            rating,
+			"comment":           comment,
+			"verified_purchase": verifiedPurchase,
+			"review_date":       time.Now(),
+			"status":            models.ReviewStatusPending,
+		},
+		"$unset": bson.M{
+			"moderated_by": "",
+			"moderated_at": "",
+		},
+	}
+
+	return r.writeWithRatings(ctx, previous.BicycleID, &previous, nil, func(sessCtx context.Context) error {
+		return updateUnchanged(sessCtx, previous, update)
+	})
+}
+
+// SetStatus records a moderation decision, adding the review to or removing it
+// from the rating aggregates. The decision settles the reports made so far.
+func (r *ReviewRepository) SetStatus(ctx context.Context, previous models.Review, status string, moderatorID primitive.ObjectID) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":       status,
+			"moderated_by": moderatorID,
+			"moderated_at": time.Now(),
+			"report_count": 0,
+		},
+	}
+	moderated := previous
+	moderated.Status = status
+
+	return r.writeWithRatings(ctx, previous.BicycleID, &previous, &moderated, func(sessCtx context.Context) error {
+		return updateUnchanged(sessCtx, previous, update)
+	})
+}
+
+// Flag hides an approved review until a moderator decides on it again
+func (r *ReviewRepository) Flag(ctx context.Context, previous models.Review) error {
+	flagged := previous
+	flagged.Status = models.ReviewStatusFlagged
+
+	return r.writeWithRatings(ctx, previous.BicycleID, &previous, &flagged, func(sessCtx context.Context) error {
+		return updateUnchanged(sessCtx, previous, bson.M{"$set": bson.M{"status": models.ReviewStatusFlagged}})
+	})
+}
+
+// voteCounters maps the kinds of review votes to the review counter they increment
+var voteCounters = map[string]string{
+	models.ReviewVoteHelpful: "helpful_count",
+	models.ReviewVoteReport:  "report_count",
+}
+
+// AddVote records a vote on an approved review and uses $inc on the matching counter
+// in the same transaction, returning the updated review. The unique vote index rejects
+// a second vote of the same kind by a customer with a duplicate key error.
+func (r *ReviewRepository) AddVote(ctx context.Context, bicycleID primitive.ObjectID, vote *models.ReviewVote) (*models.Review, error) {
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	var review models.Review
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		vote.CreatedAt = time.Now()
+		result, err := database.GetCollection("review_votes").InsertOne(sessCtx, vote)
+		if err != nil {
+			return nil, err
+		}
+		vote.ID = result.InsertedID.(primitive.ObjectID)
+
+		query := bson.M{
+			"_id":        vote.ReviewID,
+			"bicycle_id": bicycleID,
+			"status":     models.ReviewStatusApproved,
+		}
+		update := bson.M{"$inc": bson.M{voteCounters[vote.Kind]: 1}}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := database.GetCollection("reviews").FindOneAndUpdate(sessCtx, query, update, opts).Decode(&review); err != nil {
+			return nil, err
+		}
+
+		// Helpfulness orders the top reviews
+		if vote.Kind == models.ReviewVoteHelpful {
+			return nil, r.RefreshTopReviews(sessCtx, bicycleID)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+// Delete removes a review and its votes, taking an approved review out of the rating aggregates
+func (r *ReviewRepository) Delete(ctx context.Context, previous models.Review) error {
+	return r.writeWithRatings(ctx, previous.BicycleID, &previous, nil, func(sessCtx context.Context) error {
+		result, err := database.GetCollection("reviews").DeleteOne(sessCtx, unchanged(previous))
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		_, err = database.GetCollection("review_votes").DeleteMany(sessCtx, bson.M{"review_id": previous.ReviewID})
+		return err
+	})
+}
+
+// writeWithRatings runs a review write that changes a review from before to after, and
+// keeps the bicycle's rating aggregates and top reviews in step in the same transaction.
+// Writes that don't touch approved reviews leave the bicycle alone and need no transaction.
+func (r *ReviewRepository) writeWithRatings(ctx context.Context, bicycleID primitive.ObjectID, before, after *models.Review, write func(sessCtx context.Context) error) error {
+	delta := ratingDelta(before, after)
+	topChanged := (before != nil && before.IsApproved()) || (after != nil && after.IsApproved())
+	if len(delta) == 0 && !topChanged {
+		return write(ctx)
+	}
+
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if err := write(sessCtx); err != nil {
+			return nil, err
+		}
+
+		if len(delta) > 0 {
+			update := bson.M{
+				"$inc": delta,
+				"$set": bson.M{"updated_at": time.Now()},
+			}
+			if _, err := database.GetCollection("bicycles").UpdateOne(sessCtx, bson.M{"_id": bicycleID}, update); err != nil {
+				return nil, err
+			}
+			if err := refreshRatingAvg(sessCtx, bicycleID); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, r.RefreshTopReviews(sessCtx, bicycleID)
+	})
+
+	return err
+}
+
+// RefreshTopReviews copies the most helpful approved reviews of a bicycle into its top_reviews
+func (r *ReviewRepository) RefreshTopReviews(ctx context.Context, bicycleID primitive.ObjectID) error {
+	opts := options.Find().
+		SetSort(reviewSorts[models.ReviewSortHelpful]).
+		SetLimit(models.TopReviewCount)
+
+	cursor, err := database.GetCollection("reviews").Find(ctx, bson.M{"bicycle_id": bicycleID, "status": models.ReviewStatusApproved}, opts)
+	if err != nil {
+		return err
+	}
+	top := []models.Review{}
+	if err := cursor.All(ctx, &top); err != nil {
+		return err
+	}
+
+	_, err = database.GetCollection("bicycles").UpdateOne(ctx, bson.M{"_id": bicycleID}, bson.M{
+		"$set": bson.M{"top_reviews": top},
+	})
+	return err
+}
+
+// unchanged matches a review while it still has the status and rating it was read with,
+// so the rating aggregates derived from them stay exact
+func unchanged(previous models.Review) bson.M {
+	return bson.M{
+		"_id":    previous.ReviewID,
+		"status": previous.Status,
+		"rating": previous.Rating,
+	}
+}
+
+// updateUnchanged updates a review that is unchanged since it was read as previous,
+// returning mongo.ErrNoDocuments otherwise
+func updateUnchanged(ctx context.Context, previous models.Review, update bson.M) error {
+	result, err := database.GetCollection("reviews").UpdateOne(ctx, unchanged(previous), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// refreshRatingAvg recomputes rating_avg from the stored sum and count
+func refreshRatingAvg(ctx context.Context, bicycleID primitive.ObjectID) error {
+	collection := database.GetCollection("bicycles")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": bicycleID}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"rating_avg": RatingAverage("$rating_sum", "$rating_count")}}},
+	})
+	return err
+}
+
+// RatingAverage is the aggregation expression for an average rating rounded to two
+// decimals, 0 when there are no ratings
+func RatingAverage(sum, count interface{}) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$gt": bson.A{count, 0}},
+		bson.M{"$round": bson.A{bson.M{"$divide": bson.A{sum, count}}, 2}},
+		0,
+	}}
+}
+
+// ratingDelta is the $inc of the rating aggregates when a review changes from before
+// to after; nil stands for no review, and only approved reviews are counted
+func ratingDelta(before, after *models.Review) bson.M {
+	delta := map[string]int{}
+	if before != nil && before.IsApproved() {
+		delta["rating_count"]--
+		delta["rating_sum"] -= before.Rating
+		delta["rating_histogram."+strconv.Itoa(before.Rating)]--
+	}
+	if after != nil && after.IsApproved() {
+		delta["rating_count"]++
+		delta["rating_sum"] += after.Rating
+		delta["rating_histogram."+strconv.Itoa(after.Rating)]++
+	}
+
+	inc := bson.M{}
+	for field, value := range delta {
+		if value != 0 {
+			inc[field] = value
+		}
+	}
+	return inc
+}
+
+// GetQueue returns reviews with a moderation status, oldest first, with their bicycle
+func (r *ReviewRepository) GetQueue(ctx context.Context, filter models.ReviewQueueFilter) ([]models.ReviewQueueItem, int64, error) {
+	collection := database.GetCollection("reviews")
+
+	query := bson.M{"status": filter.Status}
+	total, err := collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: query}},
+		{{Key: "$sort", Value: bson.D{{Key: "review_date", Value: 1}}}},
+		{{Key: "$skip", Value: int64((filter.Page - 1) * filter.Limit)}},
+		{{Key: "$limit", Value: int64(filter.Limit)}},
+		{{Key: "$project", Value: bson.M{
+			"_id":        0,
+			"bicycle_id": 1,
+			"review":     "$$ROOT",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "bicycles",
+			"localField":   "bicycle_id",
+			"foreignField": "_id",
+			"pipeline":     bson.A{bson.M{"$project": bson.M{"model_name": 1, "brand": 1}}},
+			"as":           "bicycle",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$bicycle", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$set", Value: bson.M{
+			"model_name": "$bicycle.model_name",
+			"brand":      "$bicycle.brand",
+		}}},
+		{{Key: "$unset", Value: "bicycle"}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []models.ReviewQueueItem{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
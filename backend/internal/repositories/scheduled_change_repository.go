@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"bicycle-store/internal/database"
+	"bicycle-store/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ScheduledChangeRepository struct{}
+
+func NewScheduledChangeRepository() *ScheduledChangeRepository {
+	return &ScheduledChangeRepository{}
+}
+
+// GetAll returns scheduled changes, soonest first
+func (r *ScheduledChangeRepository) GetAll(ctx context.Context, filter models.ScheduledChangeFilter) ([]models.ScheduledChange, error) {
+	collection := database.GetCollection("scheduled_changes")
+
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if bicycleID, err := primitive.ObjectIDFromHex(filter.BicycleID); err == nil {
+		query["bicycle_id"] = bicycleID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "effective_at", Value: 1}})
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	changes := []models.ScheduledChange{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (r *ScheduledChangeRepository) Create(ctx context.Context, change *models.ScheduledChange) error {
+	collection := database.GetCollection("scheduled_changes")
+
+	change.Status = models.ChangeStatusPending
+	change.CreatedAt = time.Now()
+	change.UpdatedAt = time.Now()
+
+	result, err := collection.InsertOne(ctx, change)
+	if err != nil {
+		return err
+	}
+
+	change.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Cancel cancels a change that has not been applied yet
+func (r *ScheduledChangeRepository) Cancel(ctx context.Context, id primitive.ObjectID) error {
+	collection := database.GetCollection("scheduled_changes")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.ChangeStatusPending},
+		bson.M{"$set": bson.M{"status": models.ChangeStatusCancelled, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ClaimDue atomically takes the earliest pending change that is due, so only
+// one scheduler applies it, or a change whose claim is older than ChangeClaimTimeout.
+// It returns mongo.ErrNoDocuments when none is due.
+func (r *ScheduledChangeRepository) ClaimDue(ctx context.Context, now time.Time) (*models.ScheduledChange, error) {
+	collection := database.GetCollection("scheduled_changes")
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "effective_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var change models.ScheduledChange
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"effective_at": bson.M{"$lte": now},
+			"$or": []bson.M{
+				{"status": models.ChangeStatusPending},
+				{"status": models.ChangeStatusApplying, "updated_at": bson.M{"$lt": now.Add(-models.ChangeClaimTimeout)}},
+			},
+		},
+		bson.M{"$set": bson.M{"status": models.ChangeStatusApplying, "updated_at": now}},
+		opts,
+	).Decode(&change)
+	if err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// Finish records the outcome of applying a claimed change
+func (r *ScheduledChangeRepository) Finish(ctx context.Context, id primitive.ObjectID, applyErr error) error {
+	collection := database.GetCollection("scheduled_changes")
+
+	now := time.Now()
+	set := bson.M{"status": models.ChangeStatusApplied, "applied_at": now, "updated_at": now}
+	if applyErr != nil {
+		set = bson.M{"status": models.ChangeStatusFailed, "error": applyErr.Error(), "updated_at": now}
+	}
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
@@ -0,0 +1,228 @@
+package repositories
+
+import (
+	"bicycle-store/internal/database"
+	"bicycle-store/internal/models"
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StockAlertRepository struct{}
+
+func NewStockAlertRepository() *StockAlertRepository {
+	return &StockAlertRepository{}
+}
+
+// Create adds a waiting alert. The unique index on waiting alerts rejects a second
+// one by the same customer with a duplicate key error.
+func (r *StockAlertRepository) Create(ctx context.Context, alert *models.StockAlert) error {
+	collection := database.GetCollection("stock_alerts")
+
+	alert.Status = models.StockAlertWaiting
+	alert.CreatedAt = time.Now()
+
+	result, err := collection.InsertOne(ctx, alert)
+	if err != nil {
+		return err
+	}
+
+	alert.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Delete removes a customer's waiting alert on a bicycle, or on its variant with the given SKU
+func (r *StockAlertRepository) Delete(ctx context.Context, bicycleID, customerID primitive.ObjectID, sku string) error {
+	collection := database.GetCollection("stock_alerts")
+
+	result, err := collection.DeleteOne(ctx, bson.M{
+		"bicycle_id":  bicycleID,
+		"sku":         alertSKU(sku),
+		"customer_id": customerID,
+		"status":      models.StockAlertWaiting,
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetWaitingBicycleIDs returns which of the given bicycles the customer has a waiting alert on
+func (r *StockAlertRepository) GetWaitingBicycleIDs(ctx context.Context, customerID primitive.ObjectID, bicycleIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	collection := database.GetCollection("stock_alerts")
+
+	ids, err := collection.Distinct(ctx, "bicycle_id", bson.M{
+		"customer_id": customerID,
+		"bicycle_id":  bson.M{"$in": bicycleIDs},
+		"status":      models.StockAlertWaiting,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	waiting := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			waiting[oid] = true
+		}
+	}
+	return waiting, nil
+}
+
+// GetBicyclesWithWaitingAlerts returns the IDs of bicycles that have waiting alerts
+func (r *StockAlertRepository) GetBicyclesWithWaitingAlerts(ctx context.Context) ([]primitive.ObjectID, error) {
+	collection := database.GetCollection("stock_alerts")
+
+	ids, err := collection.Distinct(ctx, "bicycle_id", bson.M{"status": models.StockAlertWaiting})
+	if err != nil {
+		return nil, err
+	}
+
+	bicycleIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			bicycleIDs = append(bicycleIDs, oid)
+		}
+	}
+	return bicycleIDs, nil
+}
+
+// alertSKU matches the SKU of alerts; alerts on a bicycle without variants have none
+func alertSKU(sku string) interface{} {
+	if sku == "" {
+		return nil
+	}
+	return sku
+}
+
+// QueueBackInStock queues a notification for every waiting alert on a public bicycle, or
+// on its variants, whose stock is now above 0, and marks those alerts notified in the
+// same transaction, so each alert is queued exactly once however many stock increases
+// race. skus limits this to the given variants ("" for a bicycle without variants) that
+// a stock change took from 0 to positive; nil takes all of them. Returns how many
+// notifications were queued.
+func (r *StockAlertRepository) QueueBackInStock(ctx context.Context, bicycleID primitive.ObjectID, skus []string) (int, error) {
+	alertsCollection := database.GetCollection("stock_alerts")
+
+	// Most stock changes concern bicycles nobody waits for
+	waitingQuery := bson.M{"bicycle_id": bicycleID, "status": models.StockAlertWaiting}
+	count, err := alertsCollection.CountDocuments(ctx, waitingQuery, options.Count().SetLimit(1))
+	if err != nil || count == 0 {
+		return 0, err
+	}
+
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return 0, err
+	}
+	defer session.EndSession(ctx)
+
+	queued := 0
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		queued = 0
+
+		var bicycle models.Bicycle
+		opts := options.FindOne().SetProjection(bson.M{"model_name": 1, "brand": 1, "status": 1, "stock_quantity": 1, "variants": 1})
+		err := database.GetCollection("bicycles").FindOne(sessCtx, bson.M{"_id": bicycleID}, opts).Decode(&bicycle)
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !bicycle.IsPublic() {
+			return nil, nil
+		}
+
+		// What is in stock now, by SKU, with the name it is announced by
+		names := make(map[string]string)
+		if len(bicycle.Variants) == 0 {
+			if bicycle.StockQuantity > 0 {
+				names[""] = bicycle.Brand + " " + bicycle.ModelName
+			}
+		}
+		for _, variant := range bicycle.Variants {
+			if variant.StockQuantity > 0 {
+				values := make([]string, 0, len(variant.Options))
+				for _, option := range variant.Options {
+					values = append(values, option.Value)
+				}
+				names[variant.SKU] = bicycle.Brand + " " + bicycle.ModelName + " (" + strings.Join(values, ", ") + ")"
+			}
+		}
+		if skus != nil {
+			restocked := make(map[string]bool, len(skus))
+			for _, sku := range skus {
+				restocked[sku] = true
+			}
+			for sku := range names {
+				if !restocked[sku] {
+					delete(names, sku)
+				}
+			}
+		}
+		if len(names) == 0 {
+			return nil, nil
+		}
+
+		inStock := bson.A{}
+		for sku := range names {
+			inStock = append(inStock, alertSKU(sku))
+		}
+		cursor, err := alertsCollection.Find(sessCtx, bson.M{
+			"bicycle_id": bicycleID,
+			"sku":        bson.M{"$in": inStock},
+			"status":     models.StockAlertWaiting,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var alerts []models.StockAlert
+		if err := cursor.All(sessCtx, &alerts); err != nil {
+			return nil, err
+		}
+		if len(alerts) == 0 {
+			return nil, nil
+		}
+
+		now := time.Now()
+		ids := make([]primitive.ObjectID, 0, len(alerts))
+		notifications := make([]interface{}, 0, len(alerts))
+		for _, alert := range alerts {
+			ids = append(ids, alert.ID)
+			notifications = append(notifications, models.Notification{
+				CustomerID: alert.CustomerID,
+				Kind:       models.NotificationBackInStock,
+				BicycleID:  bicycleID,
+				SKU:        alert.SKU,
+				Subject:    names[alert.SKU] + " is back in stock",
+				CreatedAt:  now,
+			})
+		}
+
+		// A concurrent transaction marking the same alerts makes this one conflict and retry
+		_, err = alertsCollection.UpdateMany(sessCtx,
+			bson.M{"_id": bson.M{"$in": ids}, "status": models.StockAlertWaiting},
+			bson.M{"$set": bson.M{"status": models.StockAlertNotified, "notified_at": now}},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := database.GetCollection("notifications").InsertMany(sessCtx, notifications); err != nil {
+			return nil, err
+		}
+		queued = len(notifications)
+		return nil, nil
+	})
+
+	return queued, err
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"bicycle-store/internal/controllers"
 	"bicycle-store/internal/middleware"
+	"bicycle-store/internal/models"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -13,16 +14,46 @@ func SetupRoutes(router *gin.Engine) {
 	// Swagger
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Rate limiting
+	rateLimitStore := middleware.NewRateLimitStore()
+	apiLimit := middleware.RateLimitMiddleware(rateLimitStore, middleware.RateLimitPolicy{
+		Name:  "api",
+		Rate:  10,
+		Burst: 100,
+		Key:   middleware.KeyByAPIKey(),
+	})
+	authLimit := middleware.RateLimitMiddleware(rateLimitStore, middleware.RateLimitPolicy{
+		Name:  "auth",
+		Rate:  1.0 / 12, // 5 per minute
+		Burst: 5,
+		Key:   middleware.KeyByIP,
+	})
+	reviewLimit := middleware.RateLimitMiddleware(rateLimitStore, middleware.RateLimitPolicy{
+		Name:  "reviews",
+		Rate:  1.0 / 60, // 1 per minute
+		Burst: 3,
+		Key:   middleware.KeyByUser,
+	})
+	reviewVoteLimit := middleware.RateLimitMiddleware(rateLimitStore, middleware.RateLimitPolicy{
+		Name:  "review-votes",
+		Rate:  1.0 / 6, // 10 per minute
+		Burst: 10,
+		Key:   middleware.KeyByUser,
+	})
+
 	// API v1
 	v1 := router.Group("/api/v1")
+	v1.Use(apiLimit)
 	{
 		// Auth routes (public)
 		authController := controllers.NewAuthController()
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/register", authController.Register)
-			auth.POST("/login", authController.Login)
+			auth.POST("/register", authLimit, authController.Register)
+			auth.POST("/login", authLimit, authController.Login)
 			auth.GET("/me", middleware.AuthMiddleware(), authController.GetMe)
+			auth.GET("/oidc/login", authLimit, authController.OIDCLogin)
+			auth.GET("/oidc/callback", authLimit, authController.OIDCCallback)
 		}
 
 		// Category routes
@@ -30,7 +61,9 @@ func SetupRoutes(router *gin.Engine) {
 		categories := v1.Group("/categories")
 		{
 			categories.GET("", categoryController.GetAll)
+			categories.GET("/tree", categoryController.GetTree)
 			categories.GET("/:id", categoryController.GetByID)
+			categories.GET("/:id/breadcrumbs", categoryController.GetBreadcrumbs)
 			// Admin only
 			categories.POST("", middleware.AuthMiddleware(), middleware.AdminMiddleware(), categoryController.Create)
 			categories.PUT("/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), categoryController.Update)
@@ -39,30 +72,50 @@ func SetupRoutes(router *gin.Engine) {
 
 		// Bicycle routes
 		bicycleController := controllers.NewBicycleController()
+		reviewController := controllers.NewReviewController()
+		wishlistController := controllers.NewWishlistController()
+		recommendationController := controllers.NewRecommendationController()
 		bicycles := v1.Group("/bicycles")
 		{
 			bicycles.GET("", bicycleController.GetAll)
+			bicycles.GET("/facets", bicycleController.GetFacets)
+			bicycles.GET("/suggest", bicycleController.Suggest)
+			bicycles.GET("/compare", bicycleController.Compare)
 			bicycles.GET("/:id", bicycleController.GetByID)
+			bicycles.GET("/:id/variants", bicycleController.GetVariants)
+			bicycles.GET("/:id/reviews", reviewController.List)
+			bicycles.GET("/:id/related", recommendationController.GetRelated)
 			// Admin only
 			bicycles.POST("", middleware.AuthMiddleware(), middleware.AdminMiddleware(), bicycleController.Create)
 			bicycles.PUT("/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), bicycleController.Update)
 			bicycles.DELETE("/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), bicycleController.Delete)
-			bicycles.PATCH("/:id/stock", middleware.AuthMiddleware(), middleware.AdminMiddleware(), bicycleController.UpdateStock)
-			// Customer - add review
-			bicycles.POST("/:id/reviews", middleware.AuthMiddleware(), bicycleController.AddReview)
+			bicycles.PATCH("/:id/stock", middleware.AuthMiddleware(models.ScopeStockWrite), middleware.AdminMiddleware(), bicycleController.UpdateStock)
+			imageController := controllers.NewImageController()
+			bicycles.POST("/:id/images", middleware.AuthMiddleware(), middleware.AdminMiddleware(), imageController.Upload)
+			bicycles.PUT("/:id/images/order", middleware.AuthMiddleware(), middleware.AdminMiddleware(), imageController.Reorder)
+			bicycles.PATCH("/:id/images/:imageId", middleware.AuthMiddleware(), middleware.AdminMiddleware(), imageController.Update)
+			bicycles.DELETE("/:id/images/:imageId", middleware.AuthMiddleware(), middleware.AdminMiddleware(), imageController.Delete)
+			// Customer - reviews
+			bicycles.POST("/:id/reviews", middleware.AuthMiddleware(), reviewLimit, reviewController.Create)
+			bicycles.PUT("/:id/reviews/:reviewId", middleware.AuthMiddleware(), reviewLimit, reviewController.Update)
+			bicycles.DELETE("/:id/reviews/:reviewId", middleware.AuthMiddleware(), reviewController.Delete)
+			bicycles.POST("/:id/reviews/:reviewId/helpful", middleware.AuthMiddleware(), reviewVoteLimit, reviewController.MarkHelpful)
+			bicycles.POST("/:id/reviews/:reviewId/report", middleware.AuthMiddleware(), reviewVoteLimit, reviewController.Report)
+			// Customer - back-in-stock alerts
+			bicycles.POST("/:id/stock-alert", middleware.AuthMiddleware(), wishlistController.SubscribeStockAlert)
+			bicycles.DELETE("/:id/stock-alert", middleware.AuthMiddleware(), wishlistController.UnsubscribeStockAlert)
 		}
 
 		// Order routes
 		orderController := controllers.NewOrderController()
 		orders := v1.Group("/orders")
-		orders.Use(middleware.AuthMiddleware())
 		{
-			orders.GET("/my", orderController.GetMyOrders)
-			orders.POST("", orderController.Create)
-			orders.GET("/:id", orderController.GetByID)
+			orders.GET("/my", middleware.AuthMiddleware(models.ScopeOrdersRead), orderController.GetMyOrders)
+			orders.POST("", middleware.AuthMiddleware(models.ScopeOrdersWrite), orderController.Create)
+			orders.GET("/:id", middleware.AuthMiddleware(models.ScopeOrdersRead), orderController.GetByID)
 			// Admin only
-			orders.GET("", middleware.AdminMiddleware(), orderController.GetAll)
-			orders.PATCH("/:id/status", middleware.AdminMiddleware(), orderController.UpdateStatus)
+			orders.GET("", middleware.AuthMiddleware(models.ScopeOrdersRead), middleware.AdminMiddleware(), orderController.GetAll)
+			orders.PATCH("/:id/status", middleware.AuthMiddleware(models.ScopeOrdersWrite), middleware.AdminMiddleware(), orderController.UpdateStatus)
 		}
 
 		// Customer routes
@@ -73,6 +126,10 @@ func SetupRoutes(router *gin.Engine) {
 			customers.PUT("/profile", customerController.UpdateProfile)
 			customers.POST("/addresses", customerController.AddAddress)
 			customers.DELETE("/addresses/:type", customerController.RemoveAddress)
+			customers.GET("/me/wishlist", wishlistController.GetWishlist)
+			customers.POST("/me/wishlist", wishlistController.AddToWishlist)
+			customers.DELETE("/me/wishlist/:bicycleId", wishlistController.RemoveFromWishlist)
+			customers.GET("/me/recommendations", recommendationController.GetRecommended)
 			// Admin only
 			customers.GET("", middleware.AdminMiddleware(), customerController.GetAll)
 			customers.GET("/:id", middleware.AdminMiddleware(), customerController.GetByID)
@@ -86,5 +143,35 @@ func SetupRoutes(router *gin.Engine) {
 			reports.GET("/sales-by-category", reportController.GetSalesByCategory)
 			reports.GET("/top-selling", reportController.GetTopSellingBicycles)
 		}
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		{
+			apiKeyController := controllers.NewAPIKeyController()
+			admin.GET("/api-keys", apiKeyController.GetAll)
+			admin.POST("/api-keys", apiKeyController.Create)
+			admin.DELETE("/api-keys/:id", apiKeyController.Revoke)
+
+			admin.GET("/bicycles", bicycleController.AdminGetAll)
+			admin.POST("/bicycles/purge", bicycleController.Purge)
+			admin.GET("/bicycles/:id", bicycleController.AdminGetByID)
+			admin.POST("/bicycles/:id/restore", bicycleController.Restore)
+			admin.GET("/bicycles/:id/price-history", bicycleController.GetPriceHistory)
+
+			admin.GET("/reviews", reviewController.GetQueue)
+			admin.PATCH("/bicycles/:id/reviews/:reviewId", reviewController.Moderate)
+
+			scheduledChangeController := controllers.NewScheduledChangeController()
+			admin.POST("/bicycles/:id/scheduled-changes", scheduledChangeController.Create)
+			admin.GET("/scheduled-changes", scheduledChangeController.GetAll)
+			admin.DELETE("/scheduled-changes/:id", scheduledChangeController.Cancel)
+
+			catalogController := controllers.NewCatalogController()
+			admin.POST("/bicycles/import", catalogController.Import)
+			admin.GET("/bicycles/export", catalogController.Export)
+			admin.GET("/import-jobs", catalogController.GetImportJobs)
+			admin.GET("/import-jobs/:id", catalogController.GetImportJob)
+		}
 	}
 }
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"bicycle-store/internal/utils"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type APIKeyService struct {
+	apiKeyRepo   *repositories.APIKeyRepository
+	customerRepo *repositories.CustomerRepository
+}
+
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:   repositories.NewAPIKeyRepository(),
+		customerRepo: repositories.NewCustomerRepository(),
+	}
+}
+
+// CreateKey generates a new key for the given admin. The plaintext key is only
+// part of the returned response; the database only ever sees its hash.
+func (s *APIKeyService) CreateKey(ctx context.Context, adminID string, input models.APIKeyInput) (*models.APIKeyCreatedResponse, error) {
+	for _, scope := range input.Scopes {
+		if !models.ValidScopes[scope] {
+			return nil, errors.New("invalid scope: " + scope)
+		}
+	}
+
+	if input.ExpiresAt != nil && input.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("expires_at must be in the future")
+	}
+
+	// Orders placed with the key belong to the owner, defaulting to the admin creating it
+	ownerHex := adminID
+	if input.OwnerID != "" {
+		ownerHex = input.OwnerID
+	}
+	ownerID, err := primitive.ObjectIDFromHex(ownerHex)
+	if err != nil {
+		return nil, errors.New("invalid owner ID")
+	}
+	if _, err := s.customerRepo.GetByID(ctx, ownerID); err != nil {
+		return nil, errors.New("owner not found")
+	}
+
+	plaintext, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.APIKey{
+		Name:      input.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashAPIKey(plaintext),
+		Scopes:    input.Scopes,
+		OwnerID:   ownerID,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyCreatedResponse{
+		APIKey: *key,
+		Key:    plaintext,
+	}, nil
+}
+
+func (s *APIKeyService) GetKeys(ctx context.Context) ([]models.APIKey, error) {
+	return s.apiKeyRepo.GetAll(ctx)
+}
+
+func (s *APIKeyService) RevokeKey(ctx context.Context, keyID string) error {
+	id, err := primitive.ObjectIDFromHex(keyID)
+	if err != nil {
+		return errors.New("invalid API key ID")
+	}
+
+	if err := s.apiKeyRepo.Revoke(ctx, id); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("API key not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// Authenticate resolves a plaintext key to an active, unexpired API key and records its use
+func (s *APIKeyService) Authenticate(ctx context.Context, plaintext string) (*models.APIKey, error) {
+	key, err := s.apiKeyRepo.GetActiveByHash(ctx, utils.HashAPIKey(plaintext))
+	if err != nil {
+		return nil, errors.New("invalid API key")
+	}
+
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("API key has expired")
+	}
+
+	if err := s.apiKeyRepo.TouchLastUsed(ctx, key.ID); err != nil {
+		log.Printf("Warning: Failed to record API key usage: %v", err)
+	}
+
+	return key, nil
+}
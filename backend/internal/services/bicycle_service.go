@@ -0,0 +1,851 @@
+package services
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BicycleInUseError is returned when deleting a bicycle that open orders still contain
+type BicycleInUseError struct {
+	OpenOrders int64 `json:"open_orders"`
+}
+
+func (e *BicycleInUseError) Error() string {
+	return fmt.Sprintf("bicycle is part of %d open orders", e.OpenOrders)
+}
+
+// BicyclesNotFoundError is returned when compared bicycles don't exist or aren't public
+type BicyclesNotFoundError struct {
+	IDs []string `json:"ids"`
+}
+
+func (e *BicyclesNotFoundError) Error() string {
+	return "bicycles not found: " + strings.Join(e.IDs, ", ")
+}
+
+type BicycleService struct {
+	bicycleRepo      *repositories.BicycleRepository
+	categoryRepo     *repositories.CategoryRepository
+	orderRepo        *repositories.OrderRepository
+	priceHistoryRepo *repositories.PriceHistoryRepository
+	stockAlertRepo   *repositories.StockAlertRepository
+}
+
+func NewBicycleService() *BicycleService {
+	return &BicycleService{
+		bicycleRepo:      repositories.NewBicycleRepository(),
+		categoryRepo:     repositories.NewCategoryRepository(),
+		orderRepo:        repositories.NewOrderRepository(),
+		priceHistoryRepo: repositories.NewPriceHistoryRepository(),
+		stockAlertRepo:   repositories.NewStockAlertRepository(),
+	}
+}
+
+// GetBicycle returns a bicycle; drafts and archived bicycles are only returned with
+// includeHidden, which also leaves the price as stored instead of applying a running
+// sale
+func (s *BicycleService) GetBicycle(ctx context.Context, id primitive.ObjectID, includeHidden bool) (*models.Bicycle, error) {
+	bicycle, err := s.bicycleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if includeHidden {
+		return bicycle, nil
+	}
+	if !bicycle.IsPublic() {
+		return nil, mongo.ErrNoDocuments
+	}
+	bicycle.ApplySale(time.Now())
+	return bicycle, nil
+}
+
+// CompareBicycles lays 2 to 4 public bicycles side by side, in the order of ids
+func (s *BicycleService) CompareBicycles(ctx context.Context, ids []string) (*models.Comparison, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	seen := make(map[primitive.ObjectID]bool)
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bicycle ID %q", id)
+		}
+		if !seen[objectID] {
+			seen[objectID] = true
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+	if len(objectIDs) < models.MinCompareBicycles || len(objectIDs) > models.MaxCompareBicycles {
+		return nil, fmt.Errorf("compare %d to %d different bicycles", models.MinCompareBicycles, models.MaxCompareBicycles)
+	}
+
+	found, err := s.bicycleRepo.GetByIDs(ctx, objectIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]models.Bicycle, len(found))
+	for _, bicycle := range found {
+		if bicycle.IsPublic() {
+			byID[bicycle.ID] = bicycle
+		}
+	}
+
+	now := time.Now()
+	bicycles := make([]models.Bicycle, 0, len(objectIDs))
+	categoryIDs := make([]primitive.ObjectID, 0, len(objectIDs))
+	missing := &BicyclesNotFoundError{}
+	for _, id := range objectIDs {
+		bicycle, ok := byID[id]
+		if !ok {
+			missing.IDs = append(missing.IDs, id.Hex())
+			continue
+		}
+		bicycle.ApplySale(now)
+		bicycles = append(bicycles, bicycle)
+		categoryIDs = append(categoryIDs, bicycle.CategoryID)
+	}
+	if len(missing.IDs) > 0 {
+		return nil, missing
+	}
+
+	categoryList, err := s.categoryRepo.GetByIDs(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	categories := make(map[primitive.ObjectID]models.Category, len(categoryList))
+	for _, category := range categoryList {
+		categories[category.ID] = category
+	}
+
+	comparison := models.CompareBicycles(bicycles, categories)
+	return &comparison, nil
+}
+
+// GetPriceHistory returns the regular price changes and the sales of a bicycle
+func (s *BicycleService) GetPriceHistory(ctx context.Context, id primitive.ObjectID) (*models.PriceHistory, error) {
+	bicycle, err := s.bicycleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.priceHistoryRepo.GetByBicycle(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sales := bicycle.Sales
+	if sales == nil {
+		sales = []models.SalePrice{}
+	}
+	return &models.PriceHistory{Changes: changes, Sales: sales}, nil
+}
+
+// CreateBicycle creates a bicycle; actorID is recorded as setting its initial price
+func (s *BicycleService) CreateBicycle(ctx context.Context, input models.BicycleInput, actorID string) (*models.Bicycle, error) {
+	actor, err := primitive.ObjectIDFromHex(actorID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	variants, attributes, err := s.prepareInput(ctx, &input, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	bicycle, err := s.bicycleRepo.Create(ctx, input, variants, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordPrice(ctx, bicycle.ID, 0, bicycle.Price, actor)
+	indexBicycle(*bicycle)
+	return bicycle, nil
+}
+
+// UpdateBicycle updates a bicycle; a change of its regular price is recorded with actorID
+func (s *BicycleService) UpdateBicycle(ctx context.Context, id primitive.ObjectID, input models.BicycleInput, actorID string) (*models.Bicycle, error) {
+	actor, err := primitive.ObjectIDFromHex(actorID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	existing, err := s.bicycleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, attributes, err := s.prepareInput(ctx, &input, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	bicycle, err := s.bicycleRepo.Update(ctx, id, input, variants, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	s.afterUpdate(ctx, existing, bicycle, actor)
+	return bicycle, nil
+}
+
+// ValidateInput checks that a bicycle could be created from input, or existing updated
+// with it, without saving anything
+func (s *BicycleService) ValidateInput(ctx context.Context, input models.BicycleInput, existing *models.Bicycle) error {
+	_, _, err := s.prepareInput(ctx, &input, existing)
+	return err
+}
+
+// prepareInput validates input for creating a bicycle, or for updating existing with it
+// when existing isn't nil, and returns the variants and attributes to store. Defaults
+// and canonical units are filled in on input.
+func (s *BicycleService) prepareInput(ctx context.Context, input *models.BicycleInput, existing *models.Bicycle) ([]models.Variant, []models.AttributeValue, error) {
+	if input.ModelName == "" || input.Brand == "" {
+		return nil, nil, errors.New("model_name and brand are required")
+	}
+	if input.Price <= 0 {
+		return nil, nil, errors.New("price must be positive")
+	}
+
+	if existing == nil && input.Status == "" {
+		input.Status = models.BicycleStatusActive
+	}
+	if input.Status != "" {
+		if existing != nil && existing.Status == models.BicycleStatusArchived {
+			return nil, nil, errors.New("archived bicycles must be restored before changing their status")
+		}
+		if err := validateStatus(input.Status); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := validateCustomizationOptions(input.CustomizationOptions); err != nil {
+		return nil, nil, err
+	}
+
+	// Numeric specifications are stored in canonical units so they can be filtered
+	if err := input.Specifications.Normalize(); err != nil {
+		return nil, nil, err
+	}
+
+	// Sales kept from before must still be below a changed price
+	sales := input.Sales
+	var current []models.Variant
+	id := primitive.NilObjectID
+	if existing != nil {
+		if sales == nil {
+			sales = existing.Sales
+		}
+		current = existing.Variants
+		id = existing.ID
+
+		// With a gallery, image_url follows the primary image
+		if len(existing.Images) > 0 {
+			input.ImageURL = existing.ImageURL
+		}
+	}
+	if err := validateSales(sales, input.Price); err != nil {
+		return nil, nil, err
+	}
+
+	variants, err := buildVariants(*input, current)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkSKUs(ctx, id, input.SKU, variants); err != nil {
+		return nil, nil, err
+	}
+
+	attributes, err := s.resolveAttributes(ctx, *input)
+	if err != nil {
+		return nil, nil, err
+	}
+	return variants, attributes, nil
+}
+
+// checkSKUs fails when another bicycle than id already uses sku or a SKU of variants
+func (s *BicycleService) checkSKUs(ctx context.Context, id primitive.ObjectID, sku string, variants []models.Variant) error {
+	variantSKUs := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		variantSKUs = append(variantSKUs, variant.SKU)
+	}
+	taken, err := s.bicycleRepo.SKUsTaken(ctx, id, sku, variantSKUs)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return errors.New("sku is already used by another bicycle")
+	}
+	return nil
+}
+
+// afterUpdate follows up on a bicycle update by actor: a change of the regular price is
+// recorded, stock going up from 0 queues back-in-stock alerts and the search index is refreshed
+func (s *BicycleService) afterUpdate(ctx context.Context, before, after *models.Bicycle, actor primitive.ObjectID) {
+	if after.Price != before.Price {
+		s.recordPrice(ctx, after.ID, before.Price, after.Price, actor)
+	}
+	queueStockAlerts(ctx, s.stockAlertRepo, after.ID, restockedSKUs(before, after))
+	indexBicycle(*after)
+}
+
+// recordPrice adds a price change to the price history. The bicycle is already
+// saved at this point, so a failure is logged rather than failing the update.
+func (s *BicycleService) recordPrice(ctx context.Context, bicycleID primitive.ObjectID, previous, price float64, actor primitive.ObjectID) {
+	change := &models.PriceChange{
+		BicycleID:     bicycleID,
+		PreviousPrice: previous,
+		Price:         price,
+		ChangedBy:     actor,
+		ChangedAt:     time.Now(),
+	}
+	if err := s.priceHistoryRepo.Create(ctx, change); err != nil {
+		log.Printf("Warning: Failed to record price change of bicycle %s: %v", bicycleID.Hex(), err)
+	}
+}
+
+// validateSales checks that sales which haven't ended are below the regular price and don't overlap
+func validateSales(sales []models.SalePrice, price float64) error {
+	now := time.Now()
+	upcoming := []models.SalePrice{}
+	for _, sale := range sales {
+		if !sale.EndsAt.After(sale.StartsAt) {
+			return errors.New("sale ends_at must be after starts_at")
+		}
+		if sale.EndsAt.After(now) {
+			upcoming = append(upcoming, sale)
+		}
+	}
+
+	sort.Slice(upcoming, func(i, j int) bool {
+		return upcoming[i].StartsAt.Before(upcoming[j].StartsAt)
+	})
+	for i, sale := range upcoming {
+		if sale.Price <= 0 || sale.Price >= price {
+			return errors.New("sale price must be positive and below the regular price")
+		}
+		if i > 0 && sale.StartsAt.Before(upcoming[i-1].EndsAt) {
+			return errors.New("sales must not overlap")
+		}
+	}
+	return nil
+}
+
+// ValidateChanges checks that a change set would apply to the bicycle as it is now
+func (s *BicycleService) ValidateChanges(ctx context.Context, id primitive.ObjectID, changes models.BicycleChanges) error {
+	bicycle, err := s.bicycleRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if bicycle.Status == models.BicycleStatusArchived {
+		return errors.New("bicycle is archived")
+	}
+
+	_, err = s.changedFields(ctx, bicycle, changes)
+	return err
+}
+
+// ApplyChanges updates a bicycle with a change set on behalf of actorID. Only the fields
+// the change set names are written, so stock taken by orders meanwhile is kept.
+func (s *BicycleService) ApplyChanges(ctx context.Context, id primitive.ObjectID, changes models.BicycleChanges, actorID string) (*models.Bicycle, error) {
+	actor, err := primitive.ObjectIDFromHex(actorID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	bicycle, err := s.bicycleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if bicycle.Status == models.BicycleStatusArchived {
+		return nil, errors.New("bicycle is archived")
+	}
+
+	fields, err := s.changedFields(ctx, bicycle, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.bicycleRepo.UpdateFields(ctx, id, fields)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("bicycle is archived")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.afterUpdate(ctx, bicycle, updated, actor)
+	return updated, nil
+}
+
+// changedFields validates a change set against a bicycle and returns the bicycle fields
+// it sets, as stored
+func (s *BicycleService) changedFields(ctx context.Context, bicycle *models.Bicycle, changes models.BicycleChanges) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if changes.ModelName != nil {
+		if *changes.ModelName == "" {
+			return nil, errors.New("model_name must not be empty")
+		}
+		fields["model_name"] = *changes.ModelName
+	}
+	if changes.Brand != nil {
+		if *changes.Brand == "" {
+			return nil, errors.New("brand must not be empty")
+		}
+		fields["brand"] = *changes.Brand
+	}
+	if changes.Price != nil {
+		if *changes.Price <= 0 {
+			return nil, errors.New("price must be positive")
+		}
+		if err := validateSales(bicycle.Sales, *changes.Price); err != nil {
+			return nil, err
+		}
+		fields["price"] = *changes.Price
+	}
+	if changes.Specifications != nil {
+		// Numeric specifications are stored in canonical units so they can be filtered
+		specifications := *changes.Specifications
+		if err := specifications.Normalize(); err != nil {
+			return nil, err
+		}
+		fields["specifications"] = specifications
+	}
+	if changes.Description != nil {
+		fields["description"] = *changes.Description
+	}
+	// With a gallery, image_url follows the primary image
+	if changes.ImageURL != nil && len(bicycle.Images) == 0 {
+		fields["image_url"] = *changes.ImageURL
+	}
+	if changes.Status != nil {
+		if err := validateStatus(*changes.Status); err != nil {
+			return nil, err
+		}
+		fields["status"] = *changes.Status
+	}
+
+	// Attributes are checked against the schema of the category the bicycle ends up in
+	if changes.CategoryID != nil || changes.Attributes != nil {
+		input := mergeChanges(bicycle, changes)
+		categoryID, err := primitive.ObjectIDFromHex(input.CategoryID)
+		if err != nil {
+			return nil, errors.New("invalid category ID")
+		}
+		attributes, err := s.resolveAttributes(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		fields["category_id"] = categoryID
+		fields["attributes"] = attributes
+	}
+	return fields, nil
+}
+
+// mergeChanges builds the update input for a bicycle with a change set applied
+func mergeChanges(bicycle *models.Bicycle, changes models.BicycleChanges) models.BicycleInput {
+	input := models.BicycleInput{
+		SKU:                  bicycle.SKU,
+		ModelName:            bicycle.ModelName,
+		Brand:                bicycle.Brand,
+		Price:                bicycle.Price,
+		StockQuantity:        bicycle.StockQuantity,
+		CategoryID:           bicycle.CategoryID.Hex(),
+		Specifications:       bicycle.Specifications,
+		Attributes:           make(map[string]interface{}),
+		CustomizationOptions: bicycle.CustomizationOptions,
+		Description:          bicycle.Description,
+		ImageURL:             bicycle.ImageURL,
+	}
+	for _, attribute := range bicycle.Attributes {
+		input.Attributes[attribute.Name] = attribute.Value
+	}
+	for _, variant := range bicycle.Variants {
+		input.Variants = append(input.Variants, models.VariantInput{
+			SKU:           variant.SKU,
+			Options:       variant.Options,
+			Price:         variant.Price,
+			StockQuantity: variant.StockQuantity,
+			Barcode:       variant.Barcode,
+		})
+	}
+
+	if changes.ModelName != nil {
+		input.ModelName = *changes.ModelName
+	}
+	if changes.Brand != nil {
+		input.Brand = *changes.Brand
+	}
+	if changes.Price != nil {
+		input.Price = *changes.Price
+	}
+	if changes.CategoryID != nil {
+		input.CategoryID = *changes.CategoryID
+	}
+	if changes.Specifications != nil {
+		input.Specifications = *changes.Specifications
+	}
+	if changes.Attributes != nil {
+		input.Attributes = changes.Attributes
+	}
+	if changes.Description != nil {
+		input.Description = *changes.Description
+	}
+	if changes.ImageURL != nil {
+		input.ImageURL = *changes.ImageURL
+	}
+	if changes.Status != nil {
+		input.Status = *changes.Status
+	}
+	return input
+}
+
+// DeleteBicycle archives a bicycle unless pending, confirmed or shipped orders still contain it.
+// Archived bicycles keep their data for order history and reports until purged.
+func (s *BicycleService) DeleteBicycle(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := s.bicycleRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	openOrders, err := s.bicycleRepo.Archive(ctx, id)
+	if err != nil {
+		return err
+	}
+	if openOrders > 0 {
+		return &BicycleInUseError{OpenOrders: openOrders}
+	}
+
+	catalogIndex.RemoveBicycle(id.Hex())
+	return nil
+}
+
+// RestoreBicycle makes an archived bicycle active again
+func (s *BicycleService) RestoreBicycle(ctx context.Context, id primitive.ObjectID) (*models.Bicycle, error) {
+	bicycle, err := s.bicycleRepo.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	indexBicycle(*bicycle)
+	return bicycle, nil
+}
+
+// PurgeArchived permanently removes bicycles archived longer than retention
+// that no order refers to, and returns how many were removed
+func (s *BicycleService) PurgeArchived(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retention)
+
+	archived, err := s.bicycleRepo.GetArchivedBefore(ctx, cutoff)
+	if err != nil || len(archived) == 0 {
+		return 0, err
+	}
+
+	referenced, err := s.orderRepo.GetReferencedBicycleIDs(ctx, archived)
+	if err != nil {
+		return 0, err
+	}
+
+	unreferenced := make([]primitive.ObjectID, 0, len(archived))
+	for _, id := range archived {
+		if !referenced[id] {
+			unreferenced = append(unreferenced, id)
+		}
+	}
+	if len(unreferenced) == 0 {
+		return 0, nil
+	}
+
+	return s.bicycleRepo.DeleteArchived(ctx, unreferenced, cutoff)
+}
+
+// indexBicycle keeps autocomplete in step with the public catalog
+func indexBicycle(bicycle models.Bicycle) {
+	if bicycle.IsPublic() {
+		catalogIndex.PutBicycle(bicycle)
+	} else {
+		catalogIndex.RemoveBicycle(bicycle.ID.Hex())
+	}
+}
+
+// validateStatus accepts the statuses a bicycle can be saved with; archiving goes through DeleteBicycle
+func validateStatus(status string) error {
+	if status != models.BicycleStatusDraft && status != models.BicycleStatusActive {
+		return errors.New("status must be draft or active")
+	}
+	return nil
+}
+
+// UpdateStock changes the stock of a bicycle, or of one of its variants when sku is set.
+// Customers waiting for the bicycle, or that variant, are notified when the stock goes up from 0.
+func (s *BicycleService) UpdateStock(ctx context.Context, id primitive.ObjectID, sku string, quantity int) error {
+	// The stock after the change tells whether it went up from 0
+	var stock int
+	if sku != "" {
+		bicycle, err := s.bicycleRepo.UpdateVariantStock(ctx, id, sku, quantity)
+		if err != nil {
+			return err
+		}
+		stock = bicycle.FindVariantBySKU(sku).StockQuantity
+	} else {
+		bicycle, err := s.bicycleRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(bicycle.Variants) > 0 {
+			return errors.New("bicycle has variants, specify the sku to update")
+		}
+
+		if bicycle, err = s.bicycleRepo.UpdateStock(ctx, id, quantity); err != nil {
+			return err
+		}
+		stock = bicycle.StockQuantity
+	}
+
+	if stock > 0 && stock-quantity <= 0 {
+		queueStockAlerts(ctx, s.stockAlertRepo, id, []string{sku})
+	}
+	return nil
+}
+
+// resolveAttributes validates the input attributes against the schema of the
+// bicycle's category and returns them typed, in schema order, with their units
+func (s *BicycleService) resolveAttributes(ctx context.Context, input models.BicycleInput) ([]models.AttributeValue, error) {
+	categoryID, err := primitive.ObjectIDFromHex(input.CategoryID)
+	if err != nil {
+		return nil, errors.New("invalid category ID")
+	}
+
+	category, err := s.categoryRepo.GetByID(ctx, categoryID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("category not found")
+		}
+		return nil, err
+	}
+
+	return validateAttributes(category, input.Attributes)
+}
+
+func validateAttributes(category *models.Category, values map[string]interface{}) ([]models.AttributeValue, error) {
+	problems := []string{}
+	for name := range values {
+		if category.FindAttribute(name) == nil {
+			problems = append(problems, "unknown attribute "+name+" for category "+category.Name)
+		}
+	}
+
+	attributes := []models.AttributeValue{}
+	for _, definition := range category.Attributes {
+		raw, present := values[definition.Name]
+		if !present || raw == nil {
+			if definition.Required {
+				problems = append(problems, "attribute "+definition.Name+" is required")
+			}
+			continue
+		}
+
+		value, err := attributeValue(definition, raw)
+		if err != nil {
+			problems = append(problems, "attribute "+definition.Name+" "+err.Error())
+			continue
+		}
+		attributes = append(attributes, models.AttributeValue{
+			Name:  definition.Name,
+			Value: value,
+			Unit:  definition.Unit,
+		})
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return attributes, nil
+}
+
+// attributeValue converts a decoded JSON value to the attribute's type
+func attributeValue(definition models.AttributeDefinition, raw interface{}) (interface{}, error) {
+	switch definition.Type {
+	case models.AttributeNumber:
+		number, ok := numberValue(raw)
+		if !ok {
+			return nil, errors.New("must be a number")
+		}
+		return number, nil
+	case models.AttributeInteger:
+		number, ok := numberValue(raw)
+		if !ok || number != math.Trunc(number) {
+			return nil, errors.New("must be a whole number")
+		}
+		return int64(number), nil
+	case models.AttributeBoolean:
+		boolean, ok := raw.(bool)
+		if !ok {
+			return nil, errors.New("must be true or false")
+		}
+		return boolean, nil
+	default:
+		text, ok := raw.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		if len(definition.AllowedValues) > 0 && !containsString(definition.AllowedValues, text) {
+			return nil, errors.New("must be one of " + strings.Join(definition.AllowedValues, ", "))
+		}
+		return text, nil
+	}
+}
+
+// numberValue reads a number from JSON, or as stored, where integers aren't float64
+func numberValue(raw interface{}) (float64, bool) {
+	switch number := raw.(type) {
+	case float64:
+		return number, true
+	case int32:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	}
+	return 0, false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCustomizationOptions checks that option names are unique and that
+// surcharges only price values the option offers
+func validateCustomizationOptions(options []models.CustomizationOption) error {
+	names := make(map[string]bool)
+	for _, option := range options {
+		if option.Name == "" {
+			return errors.New("customization option name is required")
+		}
+		if names[option.Name] {
+			return errors.New("duplicate customization option: " + option.Name)
+		}
+		names[option.Name] = true
+
+		if len(option.Options) == 0 {
+			return errors.New("customization option " + option.Name + " has no values")
+		}
+
+		values := make(map[string]bool)
+		for _, value := range option.Options {
+			values[value] = true
+		}
+		for value, surcharge := range option.Surcharges {
+			if !values[value] {
+				return errors.New("customization option " + option.Name + " has a surcharge for unknown value " + value)
+			}
+			if surcharge < 0 {
+				return errors.New("customization option " + option.Name + " has a negative surcharge for " + value)
+			}
+		}
+	}
+	return nil
+}
+
+// buildVariants validates variant input against the bicycle's customization
+// options and keeps the IDs of existing variants, matched by SKU
+func buildVariants(input models.BicycleInput, existing []models.Variant) ([]models.Variant, error) {
+	existingIDs := make(map[string]primitive.ObjectID)
+	for _, variant := range existing {
+		existingIDs[variant.SKU] = variant.VariantID
+	}
+
+	offered := make(map[string]map[string]bool)
+	for _, option := range input.CustomizationOptions {
+		offered[option.Name] = make(map[string]bool)
+		for _, value := range option.Options {
+			offered[option.Name][value] = true
+		}
+	}
+
+	variants := []models.Variant{}
+	skus := make(map[string]bool)
+	combinations := make(map[string]bool)
+	// Every variant sets the same options, so selected options match one variant only
+	var axes string
+
+	for _, variantInput := range input.Variants {
+		if skus[variantInput.SKU] {
+			return nil, errors.New("duplicate variant SKU: " + variantInput.SKU)
+		}
+		skus[variantInput.SKU] = true
+
+		seen := make(map[string]bool)
+		for _, option := range variantInput.Options {
+			values, ok := offered[option.Name]
+			if !ok {
+				return nil, errors.New("variant " + variantInput.SKU + " uses unknown option: " + option.Name)
+			}
+			if !values[option.Value] {
+				return nil, errors.New("variant " + variantInput.SKU + " uses unknown value " + option.Value + " for option " + option.Name)
+			}
+			if seen[option.Name] {
+				return nil, errors.New("variant " + variantInput.SKU + " sets option " + option.Name + " more than once")
+			}
+			seen[option.Name] = true
+		}
+
+		names := make([]string, 0, len(variantInput.Options))
+		for _, option := range variantInput.Options {
+			names = append(names, option.Name)
+		}
+		sort.Strings(names)
+		if axes == "" {
+			axes = strings.Join(names, ", ")
+		} else if strings.Join(names, ", ") != axes {
+			return nil, errors.New("variant " + variantInput.SKU + " must set the same options as the other variants: " + axes)
+		}
+
+		key := combinationKey(variantInput.Options)
+		if combinations[key] {
+			return nil, errors.New("variant " + variantInput.SKU + " duplicates the options of another variant")
+		}
+		combinations[key] = true
+
+		if variantInput.Price != nil && *variantInput.Price <= 0 {
+			return nil, errors.New("variant " + variantInput.SKU + " price must be positive")
+		}
+
+		variantID, ok := existingIDs[variantInput.SKU]
+		if !ok {
+			variantID = primitive.NewObjectID()
+		}
+
+		variants = append(variants, models.Variant{
+			VariantID:     variantID,
+			SKU:           variantInput.SKU,
+			Options:       variantInput.Options,
+			Price:         variantInput.Price,
+			StockQuantity: variantInput.StockQuantity,
+			Barcode:       variantInput.Barcode,
+		})
+	}
+
+	return variants, nil
+}
+
+func combinationKey(options []models.SelectedCustomization) string {
+	parts := make([]string, 0, len(options))
+	for _, option := range options {
+		parts = append(parts, option.Name+"="+option.Value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ";")
+}
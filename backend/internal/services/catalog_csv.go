@@ -0,0 +1,303 @@
+package services
+
+import (
+	"bicycle-store/internal/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// catalogColumns are the CSV columns of a catalog row, followed by one attr:<name>
+// column per category attribute. customization_options and variants cells hold JSON.
+var catalogColumns = []string{
+	"id", "sku", "model_name", "brand", "price", "stock_quantity", "category_id", "status",
+	"description", "image_url", "frame_material", "wheel_size", "wheel_size_label", "gear_count",
+	"brake_type", "suspension", "weight", "weight_label", "max_load", "max_load_label",
+	"customization_options", "variants",
+}
+
+const attributeColumnPrefix = "attr:"
+
+// specificationColumns are the columns of Specifications; a row setting any of them
+// sets the bicycle's specifications as a whole
+var specificationColumns = []string{
+	"frame_material", "wheel_size", "wheel_size_label", "gear_count", "brake_type", "suspension",
+	"weight", "weight_label", "max_load", "max_load_label",
+}
+
+// readCSVCatalog parses a CSV catalog with a header row. Rows that can't be parsed are
+// reported as row errors; a malformed header or file fails the whole import.
+func readCSVCatalog(data []byte) ([]catalogRecord, []models.ImportRowError, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, errors.New("invalid CSV header: " + err.Error())
+	}
+	if err := validateCSVHeader(header); err != nil {
+		return nil, nil, err
+	}
+
+	var records []catalogRecord
+	var rowErrors []models.ImportRowError
+	for number := 1; ; number++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: number, Error: "expected " + strconv.Itoa(len(header)) + " columns"})
+				continue
+			}
+			return nil, nil, errors.New("invalid CSV: " + err.Error())
+		}
+
+		row, err := parseCSVRow(header, fields)
+		if err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: number, SKU: row.SKU, Error: err.Error()})
+			continue
+		}
+		records = append(records, catalogRecord{Number: number, Row: row})
+	}
+	return records, rowErrors, nil
+}
+
+func validateCSVHeader(header []string) error {
+	known := make(map[string]bool, len(catalogColumns))
+	for _, column := range catalogColumns {
+		known[column] = true
+	}
+
+	seen := make(map[string]bool, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		header[i] = column
+		if !known[column] && !(strings.HasPrefix(column, attributeColumnPrefix) && len(column) > len(attributeColumnPrefix)) {
+			return errors.New("unknown CSV column: " + column)
+		}
+		if seen[column] {
+			return errors.New("duplicate CSV column: " + column)
+		}
+		seen[column] = true
+	}
+	return nil
+}
+
+// parseCSVRow reads a row; empty cells leave their field unset
+func parseCSVRow(header, fields []string) (models.CatalogRow, error) {
+	values := make(map[string]string, len(header))
+	attributes := make(map[string]interface{})
+	for i, column := range header {
+		value := strings.TrimSpace(fields[i])
+		if value == "" {
+			continue
+		}
+		if strings.HasPrefix(column, attributeColumnPrefix) {
+			// Typed against the category's schema once the category is known
+			attributes[strings.TrimPrefix(column, attributeColumnPrefix)] = value
+			continue
+		}
+		values[column] = value
+	}
+
+	row := models.CatalogRow{ID: values["id"], SKU: values["sku"]}
+	row.ModelName = optionalText(values, "model_name")
+	row.Brand = optionalText(values, "brand")
+	row.CategoryID = optionalText(values, "category_id")
+	row.Status = optionalText(values, "status")
+	row.Description = optionalText(values, "description")
+	row.ImageURL = optionalText(values, "image_url")
+	if len(attributes) > 0 {
+		row.Attributes = attributes
+	}
+
+	if text, ok := values["price"]; ok {
+		price, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return row, errors.New("price must be a number")
+		}
+		row.Price = &price
+	}
+	if text, ok := values["stock_quantity"]; ok {
+		stock, err := strconv.Atoi(text)
+		if err != nil || stock < 0 {
+			return row, errors.New("stock_quantity must be a whole number of at least 0")
+		}
+		row.StockQuantity = &stock
+	}
+
+	if text, ok := values["customization_options"]; ok {
+		if err := json.Unmarshal([]byte(text), &row.CustomizationOptions); err != nil {
+			return row, errors.New("customization_options must be a JSON array of options")
+		}
+		if row.CustomizationOptions == nil {
+			row.CustomizationOptions = []models.CustomizationOption{}
+		}
+	}
+	if text, ok := values["variants"]; ok {
+		if err := json.Unmarshal([]byte(text), &row.Variants); err != nil {
+			return row, errors.New("variants must be a JSON array of variants")
+		}
+		if row.Variants == nil {
+			row.Variants = []models.CatalogVariant{}
+		}
+	}
+
+	for _, column := range specificationColumns {
+		if _, ok := values[column]; ok {
+			specifications, err := parseCSVSpecifications(values)
+			if err != nil {
+				return row, err
+			}
+			row.Specifications = &specifications
+			break
+		}
+	}
+
+	return row, nil
+}
+
+func parseCSVSpecifications(values map[string]string) (models.Specifications, error) {
+	specifications := models.Specifications{
+		FrameMaterial: values["frame_material"],
+		BrakeType:     values["brake_type"],
+		Suspension:    values["suspension"],
+	}
+
+	if text, ok := values["gear_count"]; ok {
+		gears, err := strconv.Atoi(text)
+		if err != nil {
+			return specifications, errors.New("gear_count must be a whole number")
+		}
+		specifications.GearCount = gears
+	}
+
+	var err error
+	if specifications.WheelSize, err = models.ParseMeasurement(values["wheel_size"]); err != nil {
+		return specifications, errors.New("wheel_size: " + err.Error())
+	}
+	if specifications.Weight, err = models.ParseMeasurement(values["weight"]); err != nil {
+		return specifications, errors.New("weight: " + err.Error())
+	}
+	if specifications.MaxLoad, err = models.ParseMeasurement(values["max_load"]); err != nil {
+		return specifications, errors.New("max_load: " + err.Error())
+	}
+
+	// Labels are trade designations kept alongside the value, e.g. 700C wheels
+	specifications.WheelSize.Label = values["wheel_size_label"]
+	specifications.Weight.Label = values["weight_label"]
+	specifications.MaxLoad.Label = values["max_load_label"]
+	return specifications, nil
+}
+
+func optionalText(values map[string]string, column string) *string {
+	if value, ok := values[column]; ok {
+		return &value
+	}
+	return nil
+}
+
+// catalogCSVHeader returns the export header for the given attribute names
+func catalogCSVHeader(attributeNames []string) []string {
+	header := append([]string{}, catalogColumns...)
+	for _, name := range attributeNames {
+		header = append(header, attributeColumnPrefix+name)
+	}
+	return header
+}
+
+// catalogCSVRecord formats a row under catalogCSVHeader(attributeNames)
+func catalogCSVRecord(row models.CatalogRow, attributeNames []string) ([]string, error) {
+	specifications := models.Specifications{}
+	if row.Specifications != nil {
+		specifications = *row.Specifications
+	}
+
+	values := map[string]string{
+		"id":               row.ID,
+		"sku":              row.SKU,
+		"model_name":       textValue(row.ModelName),
+		"brand":            textValue(row.Brand),
+		"category_id":      textValue(row.CategoryID),
+		"status":           textValue(row.Status),
+		"description":      textValue(row.Description),
+		"image_url":        textValue(row.ImageURL),
+		"frame_material":   specifications.FrameMaterial,
+		"wheel_size":       measurementText(specifications.WheelSize),
+		"wheel_size_label": specifications.WheelSize.Label,
+		"brake_type":       specifications.BrakeType,
+		"suspension":       specifications.Suspension,
+		"weight":           measurementText(specifications.Weight),
+		"weight_label":     specifications.Weight.Label,
+		"max_load":         measurementText(specifications.MaxLoad),
+		"max_load_label":   specifications.MaxLoad.Label,
+	}
+	if row.Price != nil {
+		values["price"] = strconv.FormatFloat(*row.Price, 'f', -1, 64)
+	}
+	if row.StockQuantity != nil {
+		values["stock_quantity"] = strconv.Itoa(*row.StockQuantity)
+	}
+	if specifications.GearCount > 0 {
+		values["gear_count"] = strconv.Itoa(specifications.GearCount)
+	}
+	if len(row.CustomizationOptions) > 0 {
+		options, err := json.Marshal(row.CustomizationOptions)
+		if err != nil {
+			return nil, err
+		}
+		values["customization_options"] = string(options)
+	}
+	if len(row.Variants) > 0 {
+		variants, err := json.Marshal(row.Variants)
+		if err != nil {
+			return nil, err
+		}
+		values["variants"] = string(variants)
+	}
+
+	record := make([]string, 0, len(catalogColumns)+len(attributeNames))
+	for _, column := range catalogColumns {
+		record = append(record, values[column])
+	}
+	for _, name := range attributeNames {
+		value, ok := row.Attributes[name]
+		if !ok || value == nil {
+			record = append(record, "")
+			continue
+		}
+		if number, ok := value.(float64); ok {
+			record = append(record, strconv.FormatFloat(number, 'f', -1, 64))
+		} else {
+			record = append(record, fmt.Sprint(value))
+		}
+	}
+	return record, nil
+}
+
+func textValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// measurementText writes the value of a measurement the way ParseMeasurement reads it
+// back; its label has a column of its own
+func measurementText(m models.Measurement) string {
+	if m.Value == 0 && m.Unit == "" {
+		return ""
+	}
+	return strings.TrimSpace(strconv.FormatFloat(m.Value, 'f', -1, 64) + " " + m.Unit)
+}
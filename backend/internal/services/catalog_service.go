@@ -0,0 +1,603 @@
+package services
+
+import (
+	"bicycle-store/internal/config"
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrImportTooLarge is returned for import files over the configured size
+	ErrImportTooLarge = errors.New("import file is too large")
+	// ErrCatalogFormat is returned for formats other than CSV and NDJSON
+	ErrCatalogFormat = errors.New("format must be csv or ndjson")
+)
+
+// importProgressInterval is how many rows are imported between progress updates of the job
+const importProgressInterval = 25
+
+// catalogRecord is a parsed import row with its row number
+type catalogRecord struct {
+	Number int
+	Row    models.CatalogRow
+}
+
+type CatalogService struct {
+	bicycleService *BicycleService
+	bicycleRepo    *repositories.BicycleRepository
+	categoryRepo   *repositories.CategoryRepository
+	importJobRepo  *repositories.ImportJobRepository
+	maxBytes       int64
+}
+
+func NewCatalogService() *CatalogService {
+	return &CatalogService{
+		bicycleService: NewBicycleService(),
+		bicycleRepo:    repositories.NewBicycleRepository(),
+		categoryRepo:   repositories.NewCategoryRepository(),
+		importJobRepo:  repositories.NewImportJobRepository(),
+		maxBytes:       config.AppConfig.MaxImportBytes,
+	}
+}
+
+// MaxImportBytes is the largest accepted import file
+func (s *CatalogService) MaxImportBytes() int64 {
+	return s.maxBytes
+}
+
+// CatalogFormat returns the catalog format named by format, or else by the extension
+// of fileName
+func CatalogFormat(format, fileName string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(fileName)) {
+		case ".csv":
+			format = models.CatalogFormatCSV
+		case ".ndjson", ".jsonl":
+			format = models.CatalogFormatNDJSON
+		}
+	}
+	if format != models.CatalogFormatCSV && format != models.CatalogFormatNDJSON {
+		return "", ErrCatalogFormat
+	}
+	return format, nil
+}
+
+func (s *CatalogService) GetImportJobs(ctx context.Context) ([]models.ImportJob, error) {
+	return s.importJobRepo.GetRecent(ctx, 20)
+}
+
+func (s *CatalogService) GetImportJob(ctx context.Context, id string) (*models.ImportJob, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return s.importJobRepo.GetByID(ctx, objectID)
+}
+
+// FailInterruptedImports fails the imports a previous run of the API left unfinished.
+// Call it on startup, before any import starts.
+func (s *CatalogService) FailInterruptedImports(ctx context.Context) (int64, error) {
+	return s.importJobRepo.FailInterrupted(ctx, time.Now())
+}
+
+// StartImport parses an import file and imports its rows in the background on behalf of
+// adminID, returning the queued job. Rows that can't be parsed are reported on the job;
+// a file that can't be read at all is rejected.
+func (s *CatalogService) StartImport(ctx context.Context, adminID, fileName, format string, dryRun bool, content io.Reader) (*models.ImportJob, error) {
+	actor, err := primitive.ObjectIDFromHex(adminID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, s.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil, ErrImportTooLarge
+	}
+
+	var records []catalogRecord
+	var rowErrors []models.ImportRowError
+	if format == models.CatalogFormatCSV {
+		records, rowErrors, err = readCSVCatalog(data)
+	} else {
+		records, rowErrors = readNDJSONCatalog(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records)+len(rowErrors) == 0 {
+		return nil, errors.New("import file has no rows")
+	}
+
+	job := &models.ImportJob{
+		Format:    format,
+		FileName:  fileName,
+		DryRun:    dryRun,
+		TotalRows: len(records) + len(rowErrors),
+		Processed: len(rowErrors),
+		Failed:    len(rowErrors),
+		CreatedBy: actor,
+	}
+	job.RowErrors = appendRowErrors(nil, rowErrors...)
+	if err := s.importJobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// The job outlives the request, so it gets its own copy and context
+	running := *job
+	go s.runImport(context.Background(), &running, records)
+	return job, nil
+}
+
+// readNDJSONCatalog parses one JSON catalog row per line, skipping blank lines; rows
+// are numbered by line
+func readNDJSONCatalog(data []byte) ([]catalogRecord, []models.ImportRowError) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+
+	var records []catalogRecord
+	var rowErrors []models.ImportRowError
+	for number := 1; scanner.Scan(); number++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var row models.CatalogRow
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: number, Error: "invalid JSON: " + err.Error()})
+			continue
+		}
+		records = append(records, catalogRecord{Number: number, Row: row})
+	}
+	return records, rowErrors
+}
+
+// runImport imports the rows of a job one by one, saving progress as it goes
+func (s *CatalogService) runImport(ctx context.Context, job *models.ImportJob, records []catalogRecord) {
+	defer func() {
+		if r := recover(); r != nil {
+			finished := time.Now()
+			job.Status = models.ImportStatusFailed
+			job.Error = fmt.Sprint("import stopped: ", r)
+			job.FinishedAt = &finished
+			s.saveJob(ctx, job)
+			log.Printf("Import %s panicked: %v", job.ID.Hex(), r)
+		}
+	}()
+
+	started := time.Now()
+	job.Status = models.ImportStatusRunning
+	job.StartedAt = &started
+	s.saveJob(ctx, job)
+
+	categories := make(map[string]*models.Category)
+	for i, record := range records {
+		created, err := s.importRow(ctx, record.Row, job.DryRun, job.CreatedBy, categories)
+		job.Processed++
+		switch {
+		case err != nil:
+			job.Failed++
+			job.RowErrors = appendRowErrors(job.RowErrors, models.ImportRowError{Row: record.Number, SKU: record.Row.SKU, Error: err.Error()})
+		case created:
+			job.Created++
+		default:
+			job.Updated++
+		}
+
+		if (i+1)%importProgressInterval == 0 {
+			s.saveJob(ctx, job)
+		}
+	}
+
+	sort.SliceStable(job.RowErrors, func(i, j int) bool { return job.RowErrors[i].Row < job.RowErrors[j].Row })
+	finished := time.Now()
+	job.Status = models.ImportStatusCompleted
+	job.FinishedAt = &finished
+	s.saveJob(ctx, job)
+	log.Printf("Import %s finished: %d created, %d updated, %d failed", job.ID.Hex(), job.Created, job.Updated, job.Failed)
+}
+
+func (s *CatalogService) saveJob(ctx context.Context, job *models.ImportJob) {
+	if err := s.importJobRepo.Save(ctx, job); err != nil {
+		log.Printf("Warning: Failed to save import job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+// appendRowErrors adds row errors up to MaxImportRowErrors
+func appendRowErrors(rowErrors []models.ImportRowError, more ...models.ImportRowError) []models.ImportRowError {
+	if rowErrors == nil {
+		rowErrors = []models.ImportRowError{}
+	}
+	for _, rowError := range more {
+		if len(rowErrors) == models.MaxImportRowErrors {
+			break
+		}
+		rowErrors = append(rowErrors, rowError)
+	}
+	return rowErrors
+}
+
+// importRow creates or updates the bicycle of a row, or with dryRun only validates it.
+// Returns whether a bicycle was (or would be) created.
+func (s *CatalogService) importRow(ctx context.Context, row models.CatalogRow, dryRun bool, actor primitive.ObjectID, categories map[string]*models.Category) (bool, error) {
+	if row.ID == "" && row.SKU == "" && (row.ModelName == nil || row.Brand == nil) {
+		return false, errors.New("id, sku or model_name and brand are required")
+	}
+
+	if row.CategoryID != nil {
+		category, err := s.category(ctx, *row.CategoryID, categories)
+		if err != nil {
+			return false, err
+		}
+		id := category.ID.Hex()
+		row.CategoryID = &id
+	}
+
+	existing, err := s.findImported(ctx, row)
+	if err != nil {
+		return false, err
+	}
+	if existing == nil {
+		return true, s.createImported(ctx, row, dryRun, actor, categories)
+	}
+	return false, s.updateImported(ctx, existing, row, dryRun, actor, categories)
+}
+
+// findImported returns the bicycle a row updates, or nil when it creates one. Rows match
+// by ID, then by SKU, then by model name and brand; an ID from another store matches
+// nothing, so exports can be imported elsewhere.
+func (s *CatalogService) findImported(ctx context.Context, row models.CatalogRow) (*models.Bicycle, error) {
+	if row.ID != "" {
+		id, err := primitive.ObjectIDFromHex(row.ID)
+		if err != nil {
+			return nil, errors.New("invalid id")
+		}
+		bicycle, err := s.bicycleRepo.GetByID(ctx, id)
+		if err != mongo.ErrNoDocuments {
+			return bicycle, err
+		}
+	}
+
+	if row.SKU != "" {
+		bicycle, err := s.bicycleRepo.GetBySKU(ctx, row.SKU)
+		if err != mongo.ErrNoDocuments {
+			return bicycle, err
+		}
+	}
+
+	if row.ModelName == nil || row.Brand == nil {
+		return nil, nil
+	}
+	bicycle, err := s.bicycleRepo.GetByModel(ctx, *row.ModelName, *row.Brand)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A bicycle with another SKU is another product, which the row must not overwrite
+	if row.SKU != "" && bicycle.SKU != "" {
+		return nil, errors.New("model_name and brand belong to the bicycle with SKU " + bicycle.SKU)
+	}
+	return bicycle, nil
+}
+
+func (s *CatalogService) createImported(ctx context.Context, row models.CatalogRow, dryRun bool, actor primitive.ObjectID, categories map[string]*models.Category) error {
+	if row.ModelName == nil || row.Brand == nil || row.Price == nil || row.CategoryID == nil {
+		return errors.New("new bicycles need model_name, brand, price and category_id")
+	}
+
+	input := mergeChanges(&models.Bicycle{}, row.BicycleChanges)
+	input.SKU = row.SKU
+	input.CustomizationOptions = row.CustomizationOptions
+	variants, err := variantInputs(row.Variants)
+	if err != nil {
+		return err
+	}
+	input.Variants = variants
+	if row.StockQuantity != nil {
+		if len(variants) > 0 {
+			return errors.New("stock of a bicycle with variants is set per variant")
+		}
+		input.StockQuantity = *row.StockQuantity
+	}
+	if err := s.typeAttributes(ctx, input.CategoryID, input.Attributes, categories); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return s.bicycleService.ValidateInput(ctx, input, nil)
+	}
+	_, err = s.bicycleService.CreateBicycle(ctx, input, actor.Hex())
+	return importError(err)
+}
+
+// updateImported writes the fields a row sets to its bicycle, leaving the others as they
+// are in the database, so that stock taken by orders during the import is kept
+func (s *CatalogService) updateImported(ctx context.Context, existing *models.Bicycle, row models.CatalogRow, dryRun bool, actor primitive.ObjectID, categories map[string]*models.Category) error {
+	if existing.Status == models.BicycleStatusArchived {
+		return errors.New("bicycle is archived; restore it before importing")
+	}
+
+	// Attributes of the row are merged into the bicycle's
+	changes := row.BicycleChanges
+	if row.Attributes != nil {
+		attributes := make(map[string]interface{})
+		for _, attribute := range existing.Attributes {
+			attributes[attribute.Name] = attribute.Value
+		}
+		for name, value := range row.Attributes {
+			attributes[name] = value
+		}
+		categoryID := existing.CategoryID.Hex()
+		if changes.CategoryID != nil {
+			categoryID = *changes.CategoryID
+		}
+		if err := s.typeAttributes(ctx, categoryID, attributes, categories); err != nil {
+			return err
+		}
+		changes.Attributes = attributes
+	}
+
+	fields, err := s.bicycleService.changedFields(ctx, existing, changes)
+	if err != nil {
+		return err
+	}
+	if row.SKU != "" && row.SKU != existing.SKU {
+		fields["sku"] = row.SKU
+	}
+
+	options := existing.CustomizationOptions
+	if row.CustomizationOptions != nil {
+		if err := validateCustomizationOptions(row.CustomizationOptions); err != nil {
+			return err
+		}
+		options = row.CustomizationOptions
+		fields["customization_options"] = options
+	}
+
+	// Variants, new or kept, must use the options the bicycle ends up with
+	variantInput := mergeChanges(existing, models.BicycleChanges{})
+	variantInput.CustomizationOptions = options
+	if row.Variants != nil {
+		if variantInput.Variants, err = variantInputs(row.Variants); err != nil {
+			return err
+		}
+	}
+	variants, err := buildVariants(variantInput, existing.Variants)
+	if err != nil {
+		return err
+	}
+	if row.SKU != "" || row.Variants != nil {
+		if err := s.bicycleService.checkSKUs(ctx, existing.ID, row.SKU, variants); err != nil {
+			return err
+		}
+	}
+
+	if row.StockQuantity != nil {
+		if len(variants) > 0 {
+			return errors.New("stock of a bicycle with variants is set per variant")
+		}
+		fields["stock_quantity"] = *row.StockQuantity
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	var updated *models.Bicycle
+	if row.Variants != nil {
+		updated, err = s.bicycleRepo.ReplaceVariants(ctx, existing.ID, fields, variants, stocklessVariants(row.Variants))
+	} else {
+		updated, err = s.bicycleRepo.UpdateFields(ctx, existing.ID, fields)
+	}
+	if err == mongo.ErrNoDocuments {
+		return errors.New("bicycle is archived; restore it before importing")
+	}
+	if err != nil {
+		return importError(err)
+	}
+
+	// Stock raised from 0 queues the bicycle's back-in-stock alerts
+	s.bicycleService.afterUpdate(ctx, existing, updated, actor)
+	return nil
+}
+
+// variantInputs converts imported variants; those without stock get none here
+func variantInputs(variants []models.CatalogVariant) ([]models.VariantInput, error) {
+	inputs := make([]models.VariantInput, 0, len(variants))
+	for _, variant := range variants {
+		if variant.SKU == "" || len(variant.Options) == 0 {
+			return nil, errors.New("variants need a sku and options")
+		}
+		input := models.VariantInput{
+			SKU:     variant.SKU,
+			Options: variant.Options,
+			Price:   variant.Price,
+			Barcode: variant.Barcode,
+		}
+		if variant.StockQuantity != nil {
+			if *variant.StockQuantity < 0 {
+				return nil, errors.New("variant " + variant.SKU + " stock_quantity must be at least 0")
+			}
+			input.StockQuantity = *variant.StockQuantity
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
+
+// stocklessVariants returns the SKUs of the imported variants that leave their stock as it is
+func stocklessVariants(variants []models.CatalogVariant) []string {
+	skus := []string{}
+	for _, variant := range variants {
+		if variant.StockQuantity == nil {
+			skus = append(skus, variant.SKU)
+		}
+	}
+	return skus
+}
+
+// category resolves a category ID or slug, caching categories for the rest of the import
+func (s *CatalogService) category(ctx context.Context, idOrSlug string, categories map[string]*models.Category) (*models.Category, error) {
+	if category, ok := categories[idOrSlug]; ok {
+		return category, nil
+	}
+
+	var category *models.Category
+	var err error
+	if id, parseErr := primitive.ObjectIDFromHex(idOrSlug); parseErr == nil {
+		category, err = s.categoryRepo.GetByID(ctx, id)
+	} else {
+		category, err = s.categoryRepo.GetBySlug(ctx, idOrSlug)
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("category not found: " + idOrSlug)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	categories[idOrSlug] = category
+	categories[category.ID.Hex()] = category
+	return category, nil
+}
+
+// typeAttributes converts attribute values read as text, as CSV cells are, to the type
+// the schema of the category gives them
+func (s *CatalogService) typeAttributes(ctx context.Context, categoryID string, attributes map[string]interface{}, categories map[string]*models.Category) error {
+	if len(attributes) == 0 {
+		return nil
+	}
+	category, err := s.category(ctx, categoryID, categories)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range attributes {
+		text, ok := value.(string)
+		definition := category.FindAttribute(name)
+		if !ok || definition == nil {
+			continue
+		}
+		switch definition.Type {
+		case models.AttributeNumber, models.AttributeInteger:
+			if number, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(number, 0) {
+				attributes[name] = number
+			}
+		case models.AttributeBoolean:
+			if boolean, err := strconv.ParseBool(text); err == nil {
+				attributes[name] = boolean
+			}
+		}
+	}
+	return nil
+}
+
+func importError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("sku is already used by another bicycle")
+	}
+	return err
+}
+
+// ExportCatalog writes every bicycle that isn't archived to w as CSV or NDJSON rows,
+// one per bicycle with its variants, that import back unchanged
+func (s *CatalogService) ExportCatalog(ctx context.Context, format string, w io.Writer) error {
+	if format == models.CatalogFormatNDJSON {
+		encoder := json.NewEncoder(w)
+		return s.bicycleRepo.ForEachForExport(ctx, func(bicycle *models.Bicycle) error {
+			return encoder.Encode(catalogRow(bicycle))
+		})
+	}
+
+	attributeNames, err := s.bicycleRepo.GetAttributeNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(catalogCSVHeader(attributeNames)); err != nil {
+		return err
+	}
+	err = s.bicycleRepo.ForEachForExport(ctx, func(bicycle *models.Bicycle) error {
+		record, err := catalogCSVRecord(catalogRow(bicycle), attributeNames)
+		if err != nil {
+			return err
+		}
+		return writer.Write(record)
+	})
+	writer.Flush()
+	if err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// catalogRow returns the export row of a bicycle
+func catalogRow(bicycle *models.Bicycle) models.CatalogRow {
+	categoryID := bicycle.CategoryID.Hex()
+	row := models.CatalogRow{
+		ID:                   bicycle.ID.Hex(),
+		SKU:                  bicycle.SKU,
+		CustomizationOptions: bicycle.CustomizationOptions,
+		BicycleChanges: models.BicycleChanges{
+			ModelName:      &bicycle.ModelName,
+			Brand:          &bicycle.Brand,
+			Price:          &bicycle.Price,
+			CategoryID:     &categoryID,
+			Specifications: &bicycle.Specifications,
+			Description:    &bicycle.Description,
+			ImageURL:       &bicycle.ImageURL,
+		},
+	}
+	// Bicycles created before statuses existed have none
+	if bicycle.Status != "" {
+		row.Status = &bicycle.Status
+	}
+	if len(bicycle.Attributes) > 0 {
+		row.Attributes = make(map[string]interface{}, len(bicycle.Attributes))
+		for _, attribute := range bicycle.Attributes {
+			row.Attributes[attribute.Name] = attribute.Value
+		}
+	}
+
+	if len(bicycle.Variants) == 0 {
+		row.StockQuantity = &bicycle.StockQuantity
+		return row
+	}
+	for i := range bicycle.Variants {
+		variant := &bicycle.Variants[i]
+		row.Variants = append(row.Variants, models.CatalogVariant{
+			SKU:           variant.SKU,
+			Options:       variant.Options,
+			Price:         variant.Price,
+			StockQuantity: &variant.StockQuantity,
+			Barcode:       variant.Barcode,
+		})
+	}
+	return row
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CategoryInUseError is returned when deleting a category that still has
+// subcategories or bicycles and no reassignment target was given
+type CategoryInUseError struct {
+	Children int64 `json:"children"`
+	Bicycles int64 `json:"bicycles"`
+}
+
+func (e *CategoryInUseError) Error() string {
+	return fmt.Sprintf("category has %d subcategories and %d bicycles, give a category to reassign them to", e.Children, e.Bicycles)
+}
+
+type CategoryService struct {
+	categoryRepo *repositories.CategoryRepository
+}
+
+func NewCategoryService() *CategoryService {
+	return &CategoryService{
+		categoryRepo: repositories.NewCategoryRepository(),
+	}
+}
+
+// GetCategory finds a category by ID or by slug
+func (s *CategoryService) GetCategory(ctx context.Context, idOrSlug string) (*models.Category, error) {
+	if id, err := primitive.ObjectIDFromHex(idOrSlug); err == nil {
+		return s.categoryRepo.GetByID(ctx, id)
+	}
+	return s.categoryRepo.GetBySlug(ctx, idOrSlug)
+}
+
+// GetTree returns the top-level categories with their subcategories nested
+func (s *CategoryService) GetTree(ctx context.Context) ([]*models.CategoryNode, error) {
+	categories, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[primitive.ObjectID]*models.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &models.CategoryNode{Category: category, Children: []*models.CategoryNode{}}
+	}
+
+	roots := []*models.CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	sortNodes(roots)
+	return roots, nil
+}
+
+func sortNodes(nodes []*models.CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	for _, node := range nodes {
+		sortNodes(node.Children)
+	}
+}
+
+// GetBreadcrumbs returns the path from the top-level category down to the given one
+func (s *CategoryService) GetBreadcrumbs(ctx context.Context, idOrSlug string) ([]models.Breadcrumb, error) {
+	category, err := s.GetCategory(ctx, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.categoryRepo.GetByIDs(ctx, category.Ancestors)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]models.Category, len(ancestors))
+	for _, ancestor := range ancestors {
+		byID[ancestor.ID] = ancestor
+	}
+
+	breadcrumbs := make([]models.Breadcrumb, 0, len(category.Ancestors)+1)
+	for _, id := range category.Ancestors {
+		if ancestor, ok := byID[id]; ok {
+			breadcrumbs = append(breadcrumbs, models.Breadcrumb{ID: ancestor.ID, Name: ancestor.Name, Slug: ancestor.Slug})
+		}
+	}
+	breadcrumbs = append(breadcrumbs, models.Breadcrumb{ID: category.ID, Name: category.Name, Slug: category.Slug})
+
+	return breadcrumbs, nil
+}
+
+// SubtreeIDs resolves a category ID or slug to the IDs of the category and its
+// descendants. An unknown category yields an empty list so filters match nothing.
+func (s *CategoryService) SubtreeIDs(ctx context.Context, idOrSlug string) ([]primitive.ObjectID, error) {
+	category, err := s.GetCategory(ctx, idOrSlug)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return []primitive.ObjectID{}, nil
+		}
+		return nil, err
+	}
+	return s.categoryRepo.GetSubtreeIDs(ctx, category.ID)
+}
+
+func (s *CategoryService) CreateCategory(ctx context.Context, input models.CategoryInput) (*models.Category, error) {
+	if err := validateAttributeSchema(input.Attributes); err != nil {
+		return nil, err
+	}
+
+	slug, err := categorySlug(input)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.placement(ctx, input.ParentID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := s.categoryRepo.Create(ctx, input, slug, ancestors)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("slug already in use: " + slug)
+		}
+		return nil, err
+	}
+
+	catalogIndex.PutCategory(*category)
+	return category, nil
+}
+
+// UpdateCategory replaces the category and its attribute schema, moving its
+// subtree when the parent changes. Bicycles already in the category are validated
+// against the new schema the next time they are saved.
+func (s *CategoryService) UpdateCategory(ctx context.Context, id primitive.ObjectID, input models.CategoryInput) (*models.Category, error) {
+	if err := validateAttributeSchema(input.Attributes); err != nil {
+		return nil, err
+	}
+
+	slug, err := categorySlug(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.categoryRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.placement(ctx, input.ParentID, &id)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := s.categoryRepo.Update(ctx, id, input, slug, ancestors)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("slug already in use: " + slug)
+		}
+		return nil, err
+	}
+
+	catalogIndex.PutCategory(*category)
+	return category, nil
+}
+
+// DeleteCategory deletes a category that has no subcategories or bicycles. When
+// reassignTo is set, they are moved to that category first.
+func (s *CategoryService) DeleteCategory(ctx context.Context, id primitive.ObjectID, reassignTo string) error {
+	if _, err := s.categoryRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	var target *models.Category
+	if reassignTo != "" {
+		targetID, err := primitive.ObjectIDFromHex(reassignTo)
+		if err != nil {
+			return errors.New("invalid reassignment category ID")
+		}
+		target, err = s.categoryRepo.GetByID(ctx, targetID)
+		if err != nil {
+			return errors.New("reassignment category not found")
+		}
+		if target.ID == id || containsID(target.Ancestors, id) {
+			return errors.New("cannot reassign to the category itself or one of its subcategories")
+		}
+	}
+
+	// Moved bicycles must have the attributes the target's schema asks for
+	fits := func(bicycle *models.Bicycle) error {
+		values := make(map[string]interface{}, len(bicycle.Attributes))
+		for _, attribute := range bicycle.Attributes {
+			values[attribute.Name] = attribute.Value
+		}
+		if _, err := validateAttributes(target, values); err != nil {
+			return errors.New(bicycle.Brand + " " + bicycle.ModelName + " doesn't fit " + target.Name + ": " + err.Error())
+		}
+		return nil
+	}
+
+	children, bicycles, err := s.categoryRepo.Delete(ctx, id, target, fits)
+	if err != nil {
+		return err
+	}
+	if children > 0 || bicycles > 0 {
+		return &CategoryInUseError{Children: children, Bicycles: bicycles}
+	}
+
+	catalogIndex.RemoveCategory(id.Hex())
+	return nil
+}
+
+// placement resolves a parent ID to the ancestors of a category placed under it.
+// When moving an existing category, the parent can't be the category or a descendant.
+func (s *CategoryService) placement(ctx context.Context, parentHex string, self *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if parentHex == "" {
+		return []primitive.ObjectID{}, nil
+	}
+
+	parentID, err := primitive.ObjectIDFromHex(parentHex)
+	if err != nil {
+		return nil, errors.New("invalid parent category ID")
+	}
+
+	parent, err := s.categoryRepo.GetByID(ctx, parentID)
+	if err != nil {
+		return nil, errors.New("parent category not found")
+	}
+
+	if self != nil && (parent.ID == *self || containsID(parent.Ancestors, *self)) {
+		return nil, errors.New("a category cannot be moved under itself or one of its subcategories")
+	}
+
+	ancestors := make([]primitive.ObjectID, 0, len(parent.Ancestors)+1)
+	return append(append(ancestors, parent.Ancestors...), parent.ID), nil
+}
+
+var (
+	slugPattern   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// categorySlug returns the input slug, or one generated from the name
+func categorySlug(input models.CategoryInput) (string, error) {
+	if input.Slug != "" {
+		if !slugPattern.MatchString(input.Slug) {
+			return "", errors.New("slug may only contain lowercase letters, digits and single hyphens")
+		}
+		return input.Slug, nil
+	}
+
+	slug := Slugify(input.Name)
+	if slug == "" {
+		return "", errors.New("cannot generate a slug from the category name, give one explicitly")
+	}
+	return slug, nil
+}
+
+// Slugify turns a name into a URL slug, e.g. "Mountain Bike" into "mountain-bike"
+func Slugify(name string) string {
+	return strings.Trim(slugSeparator.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validateAttributeSchema checks that attribute names are unique snake_case keys
+// and that allowed values are only given for string attributes
+func validateAttributeSchema(definitions []models.AttributeDefinition) error {
+	names := make(map[string]bool)
+	for _, definition := range definitions {
+		if !attributeNamePattern.MatchString(definition.Name) {
+			return errors.New("attribute name must be snake_case: " + definition.Name)
+		}
+		if names[definition.Name] {
+			return errors.New("duplicate attribute: " + definition.Name)
+		}
+		names[definition.Name] = true
+
+		switch definition.Type {
+		case models.AttributeString:
+			values := make(map[string]bool)
+			for _, value := range definition.AllowedValues {
+				if value == "" || values[value] {
+					return errors.New("attribute " + definition.Name + " has an empty or duplicate allowed value")
+				}
+				values[value] = true
+			}
+		case models.AttributeNumber, models.AttributeInteger, models.AttributeBoolean:
+			if len(definition.AllowedValues) > 0 {
+				return errors.New("attribute " + definition.Name + " can only have allowed values when it is a string")
+			}
+		default:
+			return errors.New("attribute " + definition.Name + " has invalid type " + definition.Type)
+		}
+	}
+	return nil
+}
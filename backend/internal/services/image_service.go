@@ -0,0 +1,275 @@
+package services
+
+import (
+	"bicycle-store/internal/config"
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"bicycle-store/internal/storage"
+	"bicycle-store/internal/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrImageTooLarge        = errors.New("image is too large")
+	ErrUnsupportedImageType = errors.New("unsupported image type, use JPEG, PNG or WebP")
+)
+
+// imageRenditions are the downscaled copies made of every gallery image, by longest side in pixels
+var imageRenditions = []struct {
+	size    string
+	maxSide int
+}{
+	{"thumbnail", 320},
+	{"medium", 960},
+}
+
+type ImageService struct {
+	bicycleRepo *repositories.BicycleRepository
+	store       storage.BlobStore
+	maxBytes    int64
+}
+
+func NewImageService() *ImageService {
+	return &ImageService{
+		bicycleRepo: repositories.NewBicycleRepository(),
+		store:       storage.NewLocalBlobStore(config.AppConfig.UploadDir, config.AppConfig.UploadBaseURL),
+		maxBytes:    config.AppConfig.MaxImageBytes,
+	}
+}
+
+// MaxImageBytes is the largest accepted upload
+func (s *ImageService) MaxImageBytes() int64 {
+	return s.maxBytes
+}
+
+// AddImage checks an uploaded image, stores it with its renditions and appends it to
+// the bicycle's gallery. The first image of a gallery is always primary.
+func (s *ImageService) AddImage(ctx context.Context, bicycleID primitive.ObjectID, content io.Reader, altText string, primary bool) (*models.Bicycle, error) {
+	if _, err := s.bicycleRepo.GetByID(ctx, bicycleID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, s.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	// The type is taken from the file content, whatever the client declared
+	contentType, ok := utils.DetectImageType(data)
+	if !ok {
+		return nil, ErrUnsupportedImageType
+	}
+	img, err := utils.DecodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	galleryImage := models.BicycleImage{
+		ImageID:     primitive.NewObjectID(),
+		AltText:     strings.TrimSpace(altText),
+		ContentType: contentType,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Renditions:  []models.ImageRendition{},
+		UploadedAt:  time.Now(),
+	}
+	if err := s.storeImage(ctx, bicycleID, &galleryImage, data, img); err != nil {
+		s.deleteBlobs(ctx, galleryImage.BlobKeys)
+		return nil, err
+	}
+
+	bicycle, err := s.bicycleRepo.AddImage(ctx, bicycleID, galleryImage)
+	if err != nil {
+		s.deleteBlobs(ctx, galleryImage.BlobKeys)
+		return nil, err
+	}
+
+	if primary || len(bicycle.Images) == 1 {
+		return s.setPrimary(ctx, bicycle, galleryImage.ImageID)
+	}
+	return bicycle, nil
+}
+
+// storeImage writes the original upload and its renditions, each in the original's
+// format and as WebP, under bicycles/<bicycle>/<image>/
+func (s *ImageService) storeImage(ctx context.Context, bicycleID primitive.ObjectID, galleryImage *models.BicycleImage, data []byte, img image.Image) error {
+	prefix := fmt.Sprintf("bicycles/%s/%s/", bicycleID.Hex(), galleryImage.ImageID.Hex())
+
+	url, err := s.put(ctx, galleryImage, prefix+"original."+utils.ImageExtensions[galleryImage.ContentType], data, galleryImage.ContentType)
+	if err != nil {
+		return err
+	}
+	galleryImage.URL = url
+
+	for _, spec := range imageRenditions {
+		resized := utils.ResizeToFit(img, spec.maxSide)
+
+		encoded, encodedType, err := utils.EncodeImage(resized, galleryImage.ContentType)
+		if err != nil {
+			return err
+		}
+		url, err := s.put(ctx, galleryImage, prefix+spec.size+"."+utils.ImageExtensions[encodedType], encoded, encodedType)
+		if err != nil {
+			return err
+		}
+
+		webp, err := utils.EncodeWebP(resized)
+		if err != nil {
+			return err
+		}
+		webpURL, err := s.put(ctx, galleryImage, prefix+spec.size+".webp", webp, "image/webp")
+		if err != nil {
+			return err
+		}
+
+		galleryImage.Renditions = append(galleryImage.Renditions, models.ImageRendition{
+			Size:    spec.size,
+			Width:   resized.Bounds().Dx(),
+			Height:  resized.Bounds().Dy(),
+			URL:     url,
+			WebPURL: webpURL,
+		})
+	}
+	return nil
+}
+
+// put stores one blob of an image, remembering its key so it can be cleaned up
+func (s *ImageService) put(ctx context.Context, galleryImage *models.BicycleImage, key string, data []byte, contentType string) (string, error) {
+	galleryImage.BlobKeys = append(galleryImage.BlobKeys, key)
+	if err := s.store.Put(ctx, key, bytes.NewReader(data), contentType); err != nil {
+		return "", err
+	}
+	return s.store.URL(key), nil
+}
+
+// deleteBlobs removes stored files; the gallery no longer refers to them, so failures are only logged
+func (s *ImageService) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("Warning: Failed to delete image file %s: %v", key, err)
+		}
+	}
+}
+
+// UpdateImage changes the alt text of a gallery image or makes it the primary image
+func (s *ImageService) UpdateImage(ctx context.Context, bicycleID, imageID primitive.ObjectID, input models.ImageUpdateInput) (*models.Bicycle, error) {
+	if input.Primary != nil && !*input.Primary {
+		return nil, errors.New("make another image primary instead")
+	}
+
+	bicycle, err := s.bicycleRepo.GetByID(ctx, bicycleID)
+	if err != nil {
+		return nil, err
+	}
+	if findImage(bicycle, imageID) == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	if input.AltText != nil {
+		bicycle, err = s.bicycleRepo.UpdateImageAltText(ctx, bicycleID, imageID, strings.TrimSpace(*input.AltText))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if input.Primary != nil {
+		return s.setPrimary(ctx, bicycle, imageID)
+	}
+	return bicycle, nil
+}
+
+// ReorderImages puts the gallery in the order of imageIDs, which must list every image once
+func (s *ImageService) ReorderImages(ctx context.Context, bicycleID primitive.ObjectID, imageIDs []string) (*models.Bicycle, error) {
+	bicycle, err := s.bicycleRepo.GetByID(ctx, bicycleID)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidOrder := errors.New("image_ids must list every image of the gallery exactly once")
+	if len(imageIDs) != len(bicycle.Images) {
+		return nil, invalidOrder
+	}
+
+	byID := make(map[primitive.ObjectID]models.BicycleImage, len(bicycle.Images))
+	for _, galleryImage := range bicycle.Images {
+		byID[galleryImage.ImageID] = galleryImage
+	}
+
+	images := make([]models.BicycleImage, 0, len(imageIDs))
+	for _, id := range imageIDs {
+		imageID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, errors.New("invalid image ID: " + id)
+		}
+		galleryImage, ok := byID[imageID]
+		if !ok {
+			return nil, invalidOrder
+		}
+		delete(byID, imageID)
+		images = append(images, galleryImage)
+	}
+
+	updated, err := s.bicycleRepo.ReorderImages(ctx, bicycleID, images)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("gallery changed while reordering, reload and try again")
+	}
+	return updated, err
+}
+
+// DeleteImage removes an image from the gallery and deletes its files. When it was
+// primary, the next image in the gallery takes its place.
+func (s *ImageService) DeleteImage(ctx context.Context, bicycleID, imageID primitive.ObjectID) (*models.Bicycle, error) {
+	bicycle, err := s.bicycleRepo.GetByID(ctx, bicycleID)
+	if err != nil {
+		return nil, err
+	}
+	removed := findImage(bicycle, imageID)
+	if removed == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	bicycle, err = s.bicycleRepo.RemoveImage(ctx, bicycleID, imageID)
+	if err != nil {
+		return nil, err
+	}
+	s.deleteBlobs(ctx, removed.BlobKeys)
+
+	if !removed.Primary {
+		return bicycle, nil
+	}
+	if len(bicycle.Images) == 0 {
+		return s.bicycleRepo.SetPrimaryImage(ctx, bicycleID, nil, "")
+	}
+	return s.setPrimary(ctx, bicycle, bicycle.Images[0].ImageID)
+}
+
+// setPrimary makes an image of the gallery primary and the bicycle's image_url
+func (s *ImageService) setPrimary(ctx context.Context, bicycle *models.Bicycle, imageID primitive.ObjectID) (*models.Bicycle, error) {
+	galleryImage := findImage(bicycle, imageID)
+	if galleryImage == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return s.bicycleRepo.SetPrimaryImage(ctx, bicycle.ID, &imageID, galleryImage.DisplayURL())
+}
+
+func findImage(bicycle *models.Bicycle, imageID primitive.ObjectID) *models.BicycleImage {
+	for i := range bicycle.Images {
+		if bicycle.Images[i].ImageID == imageID {
+			return &bicycle.Images[i]
+		}
+	}
+	return nil
+}
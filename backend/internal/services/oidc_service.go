@@ -0,0 +1,171 @@
+package services
+
+import (
+	"bicycle-store/internal/config"
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"bicycle-store/internal/utils"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrOIDCNotConfigured is returned when OIDC login is used without an issuer configured
+var ErrOIDCNotConfigured = errors.New("OIDC login is not configured")
+
+// OIDCLoginTimeout is how long a started login may take to come back from the identity provider
+const OIDCLoginTimeout = 10 * time.Minute
+
+type OIDCService struct {
+	provider     *utils.OIDCProvider
+	stateRepo    *repositories.OIDCStateRepository
+	customerRepo *repositories.CustomerRepository
+}
+
+func NewOIDCService() *OIDCService {
+	var provider *utils.OIDCProvider
+	if config.AppConfig.OIDCIssuerURL != "" {
+		provider = utils.NewOIDCProvider(
+			config.AppConfig.OIDCIssuerURL,
+			config.AppConfig.OIDCClientID,
+			config.AppConfig.OIDCClientSecret,
+			config.AppConfig.OIDCRedirectURL,
+		)
+	}
+
+	return &OIDCService{
+		provider:     provider,
+		stateRepo:    repositories.NewOIDCStateRepository(),
+		customerRepo: repositories.NewCustomerRepository(),
+	}
+}
+
+// BeginLogin stores a fresh state, nonce and PKCE verifier and returns the
+// identity provider URL to redirect the browser to, with the state that the
+// callback must come back with
+func (s *OIDCService) BeginLogin(ctx context.Context) (string, string, error) {
+	if s.provider == nil {
+		return "", "", ErrOIDCNotConfigured
+	}
+
+	state, err := utils.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := utils.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.stateRepo.Create(ctx, &models.OIDCState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(OIDCLoginTimeout),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// CompleteLogin exchanges the authorization code, links the identity to a
+// customer and issues the store's normal token
+func (s *OIDCService) CompleteLogin(ctx context.Context, code, state string) (*models.AuthResponse, error) {
+	if s.provider == nil {
+		return nil, ErrOIDCNotConfigured
+	}
+
+	oidcState, err := s.stateRepo.Consume(ctx, state)
+	if err != nil {
+		return nil, errors.New("invalid or expired login state")
+	}
+
+	claims, err := s.provider.Exchange(ctx, code, oidcState.CodeVerifier, oidcState.Nonce)
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		return nil, errors.New("identity provider login failed")
+	}
+
+	customer, err := s.linkCustomer(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateToken(customer.ID, customer.Email, customer.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		Success: true,
+		Token:   token,
+		User:    customer.ToResponse(),
+	}, nil
+}
+
+// linkCustomer finds the customer for an identity by subject, then by email,
+// creating a new customer if neither matches
+func (s *OIDCService) linkCustomer(ctx context.Context, claims *utils.OIDCClaims) (*models.Customer, error) {
+	customer, err := s.customerRepo.GetByOIDCSubject(ctx, claims.Subject)
+	if err == nil {
+		return customer, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" {
+		return nil, errors.New("identity provider did not return an email address")
+	}
+	customer, err = s.customerRepo.GetByEmail(ctx, email)
+	if err == nil {
+		// Linking by email is only safe when the provider vouches for the address;
+		// providers that leave out email_verified don't
+		if claims.EmailVerified == nil || !*claims.EmailVerified {
+			return nil, errors.New("identity provider email address is not verified")
+		}
+		if customer.OIDCSubject != "" {
+			return nil, errors.New("account is already linked to another identity")
+		}
+		return s.customerRepo.Update(ctx, customer.ID, map[string]interface{}{
+			"oidc_subject": claims.Subject,
+		})
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = email
+	}
+
+	// No password is set, so the account can only sign in through the identity provider
+	customer = &models.Customer{
+		Name:        name,
+		Email:       email,
+		OIDCSubject: claims.Subject,
+		Role:        "customer",
+	}
+	if err := s.customerRepo.Create(ctx, customer); err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
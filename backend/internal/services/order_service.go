@@ -5,22 +5,26 @@ import (
 	"bicycle-store/internal/repositories"
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type OrderService struct {
-	orderRepo    *repositories.OrderRepository
-	bicycleRepo  *repositories.BicycleRepository
-	customerRepo *repositories.CustomerRepository
+	orderRepo      *repositories.OrderRepository
+	bicycleRepo    *repositories.BicycleRepository
+	customerRepo   *repositories.CustomerRepository
+	stockAlertRepo *repositories.StockAlertRepository
 }
 
 func NewOrderService() *OrderService {
 	return &OrderService{
-		orderRepo:    repositories.NewOrderRepository(),
-		bicycleRepo:  repositories.NewBicycleRepository(),
-		customerRepo: repositories.NewCustomerRepository(),
+		orderRepo:      repositories.NewOrderRepository(),
+		bicycleRepo:    repositories.NewBicycleRepository(),
+		customerRepo:   repositories.NewCustomerRepository(),
+		stockAlertRepo: repositories.NewStockAlertRepository(),
 	}
 }
 
@@ -50,23 +54,54 @@ func (s *OrderService) CreateOrder(ctx context.Context, customerID string, input
 		if err != nil {
 			return nil, errors.New("bicycle not found: " + itemInput.BicycleID)
 		}
+		if !bicycle.IsPublic() {
+			return nil, errors.New("bicycle is not available: " + bicycle.ModelName)
+		}
+		bicycle.ApplySale(time.Now())
+
+		selected, surcharge, err := validateCustomizations(bicycle, itemInput.SelectedCustomizations)
+		if err != nil {
+			return nil, err
+		}
+
+		// Bicycles with variants are stocked and priced per option combination
+		var variant *models.Variant
+		if len(bicycle.Variants) > 0 {
+			variant = bicycle.FindVariant(selected)
+			if variant == nil {
+				return nil, errors.New("no variant of " + bicycle.ModelName + " matches the selected options")
+			}
+			if variant.StockQuantity < itemInput.Quantity {
+				return nil, errors.New("insufficient stock for: " + bicycle.ModelName + " (" + variant.SKU + ")")
+			}
+			// A variant's own price is already the price of the options that define it
+			if variant.Price != nil {
+				surcharge = waiveSurcharges(selected, variant.Options)
+			}
+		}
 
 		// Check stock
 		if bicycle.StockQuantity < itemInput.Quantity {
 			return nil, errors.New("insufficient stock for: " + bicycle.ModelName)
 		}
 
+		price := bicycle.VariantPrice(variant) + surcharge
+
 		item := models.OrderItem{
 			BicycleID:              bicycleID,
 			ModelName:              bicycle.ModelName,
 			Brand:                  bicycle.Brand,
 			Quantity:               itemInput.Quantity,
-			PriceAtPurchase:        bicycle.Price,
-			SelectedCustomizations: itemInput.SelectedCustomizations,
+			PriceAtPurchase:        price,
+			SelectedCustomizations: selected,
+		}
+		if variant != nil {
+			item.VariantID = &variant.VariantID
+			item.SKU = variant.SKU
 		}
 
 		items = append(items, item)
-		totalAmount += bicycle.Price * float64(itemInput.Quantity)
+		totalAmount += price * float64(itemInput.Quantity)
 	}
 
 	order := &models.Order{
@@ -92,6 +127,98 @@ func (s *OrderService) CreateOrder(ctx context.Context, customerID string, input
 	return order, nil
 }
 
+// waiveSurcharges clears the surcharges of the selected customizations that are among
+// options and returns the total of those left
+func waiveSurcharges(selected, options []models.SelectedCustomization) float64 {
+	var total float64
+	for i := range selected {
+		for _, option := range options {
+			if selected[i].Name == option.Name && selected[i].Value == option.Value {
+				selected[i].Surcharge = 0
+				break
+			}
+		}
+		total += selected[i].Surcharge
+	}
+	return total
+}
+
+// CustomizationError lists every invalid customization selected for a bicycle
+type CustomizationError struct {
+	ModelName string
+	Problems  []string
+}
+
+func (e *CustomizationError) Error() string {
+	return "invalid customizations for " + e.ModelName + ": " + strings.Join(e.Problems, "; ")
+}
+
+// validateCustomizations checks the selections against the bicycle's customization
+// options. It returns the selections with their surcharges filled in from the
+// bicycle, and the total surcharge per unit.
+func validateCustomizations(bicycle *models.Bicycle, selected []models.SelectedCustomization) ([]models.SelectedCustomization, float64, error) {
+	offered := make(map[string]models.CustomizationOption)
+	for _, option := range bicycle.CustomizationOptions {
+		offered[option.Name] = option
+	}
+
+	var problems []string
+	var total float64
+	validated := make([]models.SelectedCustomization, 0, len(selected))
+	counts := make(map[string]int)
+	values := make(map[string]bool)
+
+	for _, selection := range selected {
+		option, ok := offered[selection.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown option", selection.Name))
+			continue
+		}
+
+		valid := false
+		for _, value := range option.Options {
+			if value == selection.Value {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			problems = append(problems, fmt.Sprintf("%s: %q is not offered (choose from %s)", selection.Name, selection.Value, strings.Join(option.Options, ", ")))
+			continue
+		}
+
+		counts[selection.Name]++
+		if counts[selection.Name] == 2 && !option.Multiple {
+			problems = append(problems, fmt.Sprintf("%s: only one value may be selected", selection.Name))
+		}
+		if values[selection.Name+"="+selection.Value] {
+			problems = append(problems, fmt.Sprintf("%s: %q is selected more than once", selection.Name, selection.Value))
+		}
+		values[selection.Name+"="+selection.Value] = true
+
+		// Surcharges always come from the catalog, never from the client
+		surcharge := option.Surcharges[selection.Value]
+		total += surcharge
+		validated = append(validated, models.SelectedCustomization{
+			Name:      selection.Name,
+			Value:     selection.Value,
+			Surcharge: surcharge,
+		})
+	}
+
+	for _, option := range bicycle.CustomizationOptions {
+		if option.Required && counts[option.Name] == 0 {
+			problems = append(problems, fmt.Sprintf("%s: required", option.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, 0, &CustomizationError{ModelName: bicycle.ModelName, Problems: problems}
+	}
+
+	return validated, total, nil
+}
+
 func (s *OrderService) GetOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, int64, error) {
 	return s.orderRepo.GetAll(ctx, filter)
 }
@@ -133,42 +260,17 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID string, st
 		return nil, errors.New("invalid order status")
 	}
 
-	// If cancelling, use transaction to restore stock
+	// If cancelling, use transaction to restore stock, then notify customers waiting for it
 	if status == "cancelled" {
-		if err := s.orderRepo.CancelOrderWithTransaction(ctx, id); err != nil {
+		before, after, err := s.orderRepo.CancelOrderWithTransaction(ctx, id)
+		if err != nil {
 			return nil, err
 		}
+		for i := range after {
+			queueStockAlerts(ctx, s.stockAlertRepo, after[i].ID, restockedSKUs(&before[i], &after[i]))
+		}
 		return s.orderRepo.GetByID(ctx, id)
 	}
 
 	return s.orderRepo.UpdateStatus(ctx, id, status)
 }
-
-func (s *OrderService) AddReview(ctx context.Context, customerID, bicycleID string, input models.ReviewInput) (*models.Bicycle, error) {
-	custID, err := primitive.ObjectIDFromHex(customerID)
-	if err != nil {
-		return nil, errors.New("invalid customer ID")
-	}
-
-	bicID, err := primitive.ObjectIDFromHex(bicycleID)
-	if err != nil {
-		return nil, errors.New("invalid bicycle ID")
-	}
-
-	// Get customer name
-	customer, err := s.customerRepo.GetByID(ctx, custID)
-	if err != nil {
-		return nil, errors.New("customer not found")
-	}
-
-	review := models.Review{
-		ReviewID:     primitive.NewObjectID(),
-		CustomerID:   custID,
-		CustomerName: customer.Name,
-		Rating:       input.Rating,
-		Comment:      input.Comment,
-		ReviewDate:   time.Now(),
-	}
-
-	return s.bicycleRepo.AddReview(ctx, bicID, review)
-}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RecommendationService struct {
+	recommendationRepo *repositories.RecommendationRepository
+	bicycleRepo        *repositories.BicycleRepository
+	orderRepo          *repositories.OrderRepository
+}
+
+func NewRecommendationService() *RecommendationService {
+	return &RecommendationService{
+		recommendationRepo: repositories.NewRecommendationRepository(),
+		bicycleRepo:        repositories.NewBicycleRepository(),
+		orderRepo:          repositories.NewOrderRepository(),
+	}
+}
+
+// GetRelated returns the similar and bought-together bicycles of a public bicycle as of
+// the last refresh, leaving out those no longer in the catalog
+func (s *RecommendationService) GetRelated(ctx context.Context, id primitive.ObjectID) (*models.RelatedBicycles, error) {
+	bicycle, err := s.bicycleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !bicycle.IsPublic() {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	related := &models.RelatedBicycles{Similar: []models.Bicycle{}, BoughtTogether: []models.Bicycle{}}
+	recommendations, err := s.recommendationRepo.GetByBicycleIDs(ctx, []primitive.ObjectID{id})
+	if err != nil || len(recommendations) == 0 {
+		return related, err
+	}
+
+	var similarIDs, boughtTogetherIDs []primitive.ObjectID
+	for _, similar := range recommendations[0].Similar {
+		similarIDs = append(similarIDs, similar.BicycleID)
+	}
+	for _, coPurchase := range recommendations[0].BoughtTogether {
+		boughtTogetherIDs = append(boughtTogetherIDs, coPurchase.BicycleID)
+	}
+
+	byID, err := s.publicBicycles(ctx, append(similarIDs, boughtTogetherIDs...))
+	if err != nil {
+		return nil, err
+	}
+	related.Similar = orderedBicycles(byID, similarIDs, models.RelatedBicycleCount)
+	related.BoughtTogether = orderedBicycles(byID, boughtTogetherIDs, models.RelatedBicycleCount)
+	return related, nil
+}
+
+// GetRecommendedFor returns up to limit public bicycles for a customer, from the related
+// bicycles of everything they ordered: those most often bought together with their
+// bicycles first, then the most similar. Bicycles they ordered are left out; customers
+// without orders get none.
+func (s *RecommendationService) GetRecommendedFor(ctx context.Context, customerID primitive.ObjectID, limit int) ([]models.Bicycle, error) {
+	purchased, err := s.orderRepo.GetPurchasedBicycleIDs(ctx, customerID)
+	if err != nil || len(purchased) == 0 {
+		return []models.Bicycle{}, err
+	}
+
+	recommendations, err := s.recommendationRepo.GetByBicycleIDs(ctx, purchased)
+	if err != nil {
+		return nil, err
+	}
+
+	// A co-purchase outweighs a similarity score, which is at most 6
+	scores := make(map[primitive.ObjectID]float64)
+	for _, recommendation := range recommendations {
+		for _, coPurchase := range recommendation.BoughtTogether {
+			scores[coPurchase.BicycleID] += 10 * float64(coPurchase.Count)
+		}
+		for _, similar := range recommendation.Similar {
+			scores[similar.BicycleID] += similar.Score
+		}
+	}
+	for _, id := range purchased {
+		delete(scores, id)
+	}
+
+	candidates := make([]primitive.ObjectID, 0, len(scores))
+	for id := range scores {
+		candidates = append(candidates, id)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if scores[candidates[i]] != scores[candidates[j]] {
+			return scores[candidates[i]] > scores[candidates[j]]
+		}
+		return candidates[i].Hex() < candidates[j].Hex()
+	})
+
+	byID, err := s.publicBicycles(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	return orderedBicycles(byID, candidates, limit), nil
+}
+
+// Refresh recomputes the related bicycles of the whole catalog. Returns how many
+// bicycles have recommendations.
+func (s *RecommendationService) Refresh(ctx context.Context) (int64, error) {
+	return s.recommendationRepo.Refresh(ctx)
+}
+
+// publicBicycles fetches the given bicycles that are in the catalog, with running sales applied
+func (s *RecommendationService) publicBicycles(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]models.Bicycle, error) {
+	byID := make(map[primitive.ObjectID]models.Bicycle, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	bicycles, err := s.bicycleRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, bicycle := range bicycles {
+		if bicycle.IsPublic() {
+			bicycle.ApplySale(now)
+			byID[bicycle.ID] = bicycle
+		}
+	}
+	return byID, nil
+}
+
+// orderedBicycles returns up to limit of the fetched bicycles, in the order of ids
+func orderedBicycles(byID map[primitive.ObjectID]models.Bicycle, ids []primitive.ObjectID, limit int) []models.Bicycle {
+	bicycles := []models.Bicycle{}
+	for _, id := range ids {
+		if len(bicycles) == limit {
+			break
+		}
+		if bicycle, ok := byID[id]; ok {
+			bicycles = append(bicycles, bicycle)
+		}
+	}
+	return bicycles
+}
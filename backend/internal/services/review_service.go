@@ -0,0 +1,254 @@
+package services
+
+import (
+	"bicycle-store/internal/config"
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrReviewAccessDenied is returned when a customer changes a review they didn't write
+	ErrReviewAccessDenied = errors.New("only the author can change this review")
+	// ErrVerifiedPurchaseRequired is returned when REQUIRE_VERIFIED_PURCHASE is set and
+	// the customer has no delivered order of the bicycle
+	ErrVerifiedPurchaseRequired = errors.New("only customers who received this bicycle can review it")
+	// ErrOwnReview is returned when customers vote on or report their own review
+	ErrOwnReview = errors.New("you can't vote on your own review")
+	// ErrAlreadyVoted is returned when a customer marks a review helpful, or reports it, a second time
+	ErrAlreadyVoted = errors.New("you have already voted on this review")
+)
+
+type ReviewService struct {
+	reviewRepo   *repositories.ReviewRepository
+	bicycleRepo  *repositories.BicycleRepository
+	customerRepo *repositories.CustomerRepository
+	orderRepo    *repositories.OrderRepository
+}
+
+func NewReviewService() *ReviewService {
+	return &ReviewService{
+		reviewRepo:   repositories.NewReviewRepository(),
+		bicycleRepo:  repositories.NewBicycleRepository(),
+		customerRepo: repositories.NewCustomerRepository(),
+		orderRepo:    repositories.NewOrderRepository(),
+	}
+}
+
+// GetReviews returns a page of the approved reviews of a public bicycle, returning
+// mongo.ErrNoDocuments when the bicycle isn't in the catalog
+func (s *ReviewService) GetReviews(ctx context.Context, bicycleID primitive.ObjectID, filter models.ReviewFilter) ([]models.Review, int64, error) {
+	if !repositories.IsReviewSort(filter.Sort) {
+		return nil, 0, errors.New("sort must be newest, highest or helpful")
+	}
+
+	bicycle, err := s.bicycleRepo.GetByID(ctx, bicycleID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !bicycle.IsPublic() {
+		return nil, 0, mongo.ErrNoDocuments
+	}
+
+	return s.reviewRepo.GetByBicycle(ctx, bicycleID, filter)
+}
+
+// AddReview adds a customer's review to a bicycle; it is shown once a moderator approves it.
+// A customer has one review per bicycle, so reviewing again updates the existing review
+// and created is false.
+func (s *ReviewService) AddReview(ctx context.Context, customerID, bicycleID string, input models.ReviewInput) (review *models.Review, created bool, err error) {
+	custID, err := primitive.ObjectIDFromHex(customerID)
+	if err != nil {
+		return nil, false, errors.New("invalid customer ID")
+	}
+
+	bicID, err := primitive.ObjectIDFromHex(bicycleID)
+	if err != nil {
+		return nil, false, errors.New("invalid bicycle ID")
+	}
+
+	// Get customer name
+	customer, err := s.customerRepo.GetByID(ctx, custID)
+	if err != nil {
+		return nil, false, errors.New("customer not found")
+	}
+
+	bicycle, err := s.bicycleRepo.GetByID(ctx, bicID)
+	if err != nil || !bicycle.IsPublic() {
+		return nil, false, errors.New("bicycle not found")
+	}
+
+	verified, err := s.verifyPurchase(ctx, custID, bicID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	review = &models.Review{
+		ReviewID:         primitive.NewObjectID(),
+		BicycleID:        bicID,
+		CustomerID:       custID,
+		CustomerName:     customer.Name,
+		Rating:           input.Rating,
+		Comment:          input.Comment,
+		ReviewDate:       time.Now(),
+		VerifiedPurchase: verified,
+		Status:           models.ReviewStatusPending,
+	}
+
+	err = s.reviewRepo.Create(ctx, review)
+	if err == nil {
+		return review, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, false, err
+	}
+
+	// The customer has reviewed this bicycle before
+	existing, err := s.reviewRepo.GetByCustomer(ctx, bicID, custID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.reviewRepo.Update(ctx, *existing, input.Rating, input.Comment, verified); err != nil {
+		return nil, false, reviewChanged(err)
+	}
+	review, err = s.reviewRepo.GetByID(ctx, bicID, existing.ReviewID)
+	return review, false, err
+}
+
+// verifyPurchase reports whether the customer has received the bicycle, and refuses
+// the review when the deployment only accepts reviews from verified buyers
+func (s *ReviewService) verifyPurchase(ctx context.Context, customerID, bicycleID primitive.ObjectID) (bool, error) {
+	verified, err := s.orderRepo.HasDelivered(ctx, customerID, bicycleID)
+	if err != nil {
+		return false, err
+	}
+	if !verified && config.AppConfig.RequireVerifiedPurchase {
+		return false, ErrVerifiedPurchaseRequired
+	}
+	return verified, nil
+}
+
+// UpdateReview changes the rating and comment of a customer's own review,
+// which then goes back to the moderation queue
+func (s *ReviewService) UpdateReview(ctx context.Context, customerID string, bicycleID, reviewID primitive.ObjectID, input models.ReviewInput) (*models.Review, error) {
+	review, err := s.reviewRepo.GetByID(ctx, bicycleID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.CustomerID.Hex() != customerID {
+		return nil, ErrReviewAccessDenied
+	}
+
+	verified, err := s.verifyPurchase(ctx, review.CustomerID, bicycleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reviewRepo.Update(ctx, *review, input.Rating, input.Comment, verified); err != nil {
+		return nil, reviewChanged(err)
+	}
+	return s.reviewRepo.GetByID(ctx, bicycleID, reviewID)
+}
+
+// DeleteReview removes a review; customers may only delete their own, admins any
+func (s *ReviewService) DeleteReview(ctx context.Context, userID string, isAdmin bool, bicycleID, reviewID primitive.ObjectID) error {
+	review, err := s.reviewRepo.GetByID(ctx, bicycleID, reviewID)
+	if err != nil {
+		return err
+	}
+	if !isAdmin && review.CustomerID.Hex() != userID {
+		return ErrReviewAccessDenied
+	}
+
+	return reviewChanged(s.reviewRepo.Delete(ctx, *review))
+}
+
+// Vote marks an approved review helpful or reports it, once per customer. A review
+// reaching REVIEW_REPORT_THRESHOLD reports is flagged and hidden until moderated.
+func (s *ReviewService) Vote(ctx context.Context, customerID string, bicycleID, reviewID primitive.ObjectID, kind, reason string) (*models.Review, error) {
+	custID, err := primitive.ObjectIDFromHex(customerID)
+	if err != nil {
+		return nil, errors.New("invalid customer ID")
+	}
+
+	review, err := s.reviewRepo.GetByID(ctx, bicycleID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if !review.IsApproved() {
+		return nil, mongo.ErrNoDocuments
+	}
+	if review.CustomerID == custID {
+		return nil, ErrOwnReview
+	}
+
+	vote := &models.ReviewVote{
+		ReviewID:   reviewID,
+		CustomerID: custID,
+		Kind:       kind,
+		Reason:     reason,
+	}
+	review, err = s.reviewRepo.AddVote(ctx, bicycleID, vote)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrAlreadyVoted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == models.ReviewVoteReport && review.ReportCount >= config.AppConfig.ReviewReportThreshold {
+		err := s.reviewRepo.Flag(ctx, *review)
+		if err == nil {
+			review.Status = models.ReviewStatusFlagged
+			return review, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		// No match means the review was moderated, edited or flagged by another report
+		// meanwhile, so its stored status is returned
+		return s.reviewRepo.GetByID(ctx, bicycleID, reviewID)
+	}
+	return review, nil
+}
+
+// GetModerationQueue lists reviews with a moderation status, oldest first
+func (s *ReviewService) GetModerationQueue(ctx context.Context, filter models.ReviewQueueFilter) ([]models.ReviewQueueItem, int64, error) {
+	if filter.Status != models.ReviewStatusPending && filter.Status != models.ReviewStatusFlagged &&
+		filter.Status != models.ReviewStatusApproved && filter.Status != models.ReviewStatusRejected {
+		return nil, 0, errors.New("status must be pending, flagged, approved or rejected")
+	}
+	return s.reviewRepo.GetQueue(ctx, filter)
+}
+
+// ModerateReview approves or rejects a review on behalf of an admin
+func (s *ReviewService) ModerateReview(ctx context.Context, adminID string, bicycleID, reviewID primitive.ObjectID, status string) (*models.Review, error) {
+	moderator, err := primitive.ObjectIDFromHex(adminID)
+	if err != nil {
+		return nil, errors.New("invalid admin ID")
+	}
+
+	review, err := s.reviewRepo.GetByID(ctx, bicycleID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reviewRepo.SetStatus(ctx, *review, status, moderator); err != nil {
+		return nil, reviewChanged(err)
+	}
+	return s.reviewRepo.GetByID(ctx, bicycleID, reviewID)
+}
+
+// reviewChanged explains a review update that no longer matched: the review was read
+// just before, so it was edited, moderated or deleted in the meantime
+func reviewChanged(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return errors.New("the review was changed meanwhile, try again")
+	}
+	return err
+}
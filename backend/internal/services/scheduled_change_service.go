@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ScheduledChangeService struct {
+	changeRepo     *repositories.ScheduledChangeRepository
+	bicycleService *BicycleService
+}
+
+func NewScheduledChangeService() *ScheduledChangeService {
+	return &ScheduledChangeService{
+		changeRepo:     repositories.NewScheduledChangeRepository(),
+		bicycleService: NewBicycleService(),
+	}
+}
+
+func (s *ScheduledChangeService) GetChanges(ctx context.Context, filter models.ScheduledChangeFilter) ([]models.ScheduledChange, error) {
+	return s.changeRepo.GetAll(ctx, filter)
+}
+
+// ScheduleChange stores a change set to apply to a bicycle at input.EffectiveAt.
+// It is validated against the bicycle now and again when it is applied.
+func (s *ScheduledChangeService) ScheduleChange(ctx context.Context, adminID, bicycleID string, input models.ScheduledChangeInput) (*models.ScheduledChange, error) {
+	bicID, err := primitive.ObjectIDFromHex(bicycleID)
+	if err != nil {
+		return nil, errors.New("invalid bicycle ID")
+	}
+
+	createdBy, err := primitive.ObjectIDFromHex(adminID)
+	if err != nil {
+		return nil, errors.New("invalid admin ID")
+	}
+
+	if !input.EffectiveAt.After(time.Now()) {
+		return nil, errors.New("effective_at must be in the future")
+	}
+	if input.Changes.IsEmpty() {
+		return nil, errors.New("changes must set at least one field")
+	}
+
+	if err := s.bicycleService.ValidateChanges(ctx, bicID, input.Changes); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("bicycle not found")
+		}
+		return nil, err
+	}
+
+	change := &models.ScheduledChange{
+		BicycleID:   bicID,
+		Changes:     input.Changes,
+		EffectiveAt: input.EffectiveAt,
+		CreatedBy:   createdBy,
+	}
+	if err := s.changeRepo.Create(ctx, change); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// CancelChange cancels a pending change
+func (s *ScheduledChangeService) CancelChange(ctx context.Context, id string) error {
+	changeID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid change ID")
+	}
+
+	return s.changeRepo.Cancel(ctx, changeID)
+}
+
+// ApplyDue applies every change that is due, oldest first, and returns how many were applied.
+// A change that no longer applies, e.g. because its bicycle was archived, is marked failed.
+func (s *ScheduledChangeService) ApplyDue(ctx context.Context) (int, error) {
+	applied := 0
+	for {
+		change, err := s.changeRepo.ClaimDue(ctx, time.Now())
+		if err == mongo.ErrNoDocuments {
+			return applied, nil
+		}
+		if err != nil {
+			return applied, err
+		}
+
+		_, applyErr := s.bicycleService.ApplyChanges(ctx, change.BicycleID, change.Changes, change.CreatedBy.Hex())
+		if applyErr != nil {
+			log.Printf("Warning: Scheduled change %s for bicycle %s failed: %v", change.ID.Hex(), change.BicycleID.Hex(), applyErr)
+		} else {
+			applied++
+		}
+
+		if err := s.changeRepo.Finish(ctx, change.ID, applyErr); err != nil {
+			return applied, err
+		}
+	}
+}
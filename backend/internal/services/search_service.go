@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	vocabularyTTL  = 5 * time.Minute
+	maxSuggestions = 5
+)
+
+// SearchService answers autocomplete queries and suggests corrected queries when
+// a catalog search finds nothing. The "did you mean" vocabulary of brands and
+// model names is loaded from the catalog and cached.
+type SearchService struct {
+	bicycleRepo  *repositories.BicycleRepository
+	categoryRepo *repositories.CategoryRepository
+
+	mu       sync.Mutex
+	phrases  []string
+	words    []string
+	loadedAt time.Time
+}
+
+func NewSearchService() *SearchService {
+	return &SearchService{
+		bicycleRepo:  repositories.NewBicycleRepository(),
+		categoryRepo: repositories.NewCategoryRepository(),
+	}
+}
+
+// RebuildIndex loads every model name, brand and category into the autocomplete index
+func (s *SearchService) RebuildIndex(ctx context.Context) error {
+	bicycles, err := s.bicycleRepo.GetSearchTerms(ctx)
+	if err != nil {
+		return err
+	}
+
+	categories, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	catalogIndex.Replace(bicycles, categories)
+	return nil
+}
+
+// Autocomplete returns the top prefix matches from the in-memory index
+func (s *SearchService) Autocomplete(prefix string, limit int) []models.SearchSuggestion {
+	return catalogIndex.Search(prefix, limit)
+}
+
+// Suggest returns up to five "did you mean" alternatives for a search query.
+// Whole brands and model names close to the query come first, followed by the
+// query with each misspelled word replaced by its closest vocabulary word.
+func (s *SearchService) Suggest(ctx context.Context, query string) ([]string, error) {
+	phrases, words, err := s.vocabulary(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := strings.Join(tokenize(query), " ")
+	if normalized == "" {
+		return []string{}, nil
+	}
+
+	type candidate struct {
+		text     string
+		distance int
+	}
+	candidates := []candidate{}
+	seen := map[string]bool{normalized: true}
+
+	for _, phrase := range phrases {
+		key := strings.Join(tokenize(phrase), " ")
+		if seen[key] {
+			continue
+		}
+		if distance := levenshtein(normalized, key); distance <= typoTolerance(normalized) {
+			seen[key] = true
+			candidates = append(candidates, candidate{text: phrase, distance: distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	// Correct the query word by word
+	corrected := []string{}
+	changed := false
+	for _, token := range tokenize(query) {
+		best := closestWord(token, words)
+		if best != token {
+			changed = true
+		}
+		corrected = append(corrected, best)
+	}
+	if changed {
+		text := strings.Join(corrected, " ")
+		if !seen[text] {
+			candidates = append(candidates, candidate{text: text})
+		}
+	}
+
+	suggestions := []string{}
+	for _, c := range candidates {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		suggestions = append(suggestions, c.text)
+	}
+	return suggestions, nil
+}
+
+func (s *SearchService) vocabulary(ctx context.Context) ([]string, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.phrases != nil && time.Since(s.loadedAt) < vocabularyTTL {
+		return s.phrases, s.words, nil
+	}
+
+	brands, modelNames, err := s.bicycleRepo.GetVocabulary(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	phrases := append(brands, modelNames...)
+	wordSet := make(map[string]bool)
+	for _, phrase := range phrases {
+		for _, word := range tokenize(phrase) {
+			wordSet[word] = true
+		}
+	}
+	words := make([]string, 0, len(wordSet))
+	for word := range wordSet {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	s.phrases = phrases
+	s.words = words
+	s.loadedAt = time.Now()
+	return s.phrases, s.words, nil
+}
+
+// closestWord returns the vocabulary word nearest to token within the typo
+// tolerance, or token itself when it is already known or nothing is close
+func closestWord(token string, words []string) string {
+	best := token
+	bestDistance := typoTolerance(token) + 1
+	for _, word := range words {
+		if word == token {
+			return token
+		}
+		if distance := levenshtein(token, word); distance < bestDistance {
+			best = word
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// typoTolerance is the number of edits allowed for a term of this length
+func typoTolerance(term string) int {
+	switch length := len([]rune(term)); {
+	case length < 3:
+		return 0
+	case length < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
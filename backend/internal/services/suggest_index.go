@@ -0,0 +1,340 @@
+package services
+
+import (
+	"bicycle-store/internal/models"
+	"strings"
+	"sync"
+)
+
+// Kinds of autocomplete suggestions
+const (
+	SuggestionModel    = "model"
+	SuggestionBrand    = "brand"
+	SuggestionCategory = "category"
+)
+
+// suggestIndex is an in-memory trie of model names, brands and category names
+// used for search-as-you-type. Every word of a term is indexed, so "pro" finds
+// "Trailblazer Pro 29". It is shared by all services in the process, rebuilt
+// on startup and kept current by the bicycle and category services.
+type suggestIndex struct {
+	mu      sync.RWMutex
+	root    *trieNode
+	entries map[string]*suggestEntry
+	// bicycles remembers what each bicycle contributed so updates can remove it
+	bicycles map[string]models.Bicycle
+	// bulk defers ranking while Replace loads the whole catalog
+	bulk bool
+}
+
+// trieNode caches the best matches of its subtree so lookups never walk it
+type trieNode struct {
+	prefix   string
+	children map[rune]*trieNode
+	entries  map[string]bool
+	top      []*suggestEntry
+}
+
+type suggestEntry struct {
+	suggestion models.SearchSuggestion
+	normalized string
+	// refs counts the bicycles sharing a brand
+	refs int
+}
+
+// maxSuggestLimit is the number of matches cached per trie node
+const maxSuggestLimit = 20
+
+var catalogIndex = newSuggestIndex()
+
+func newSuggestIndex() *suggestIndex {
+	return &suggestIndex{
+		root:     newTrieNode(""),
+		entries:  make(map[string]*suggestEntry),
+		bicycles: make(map[string]models.Bicycle),
+	}
+}
+
+func newTrieNode(prefix string) *trieNode {
+	return &trieNode{
+		prefix:   prefix,
+		children: make(map[rune]*trieNode),
+		entries:  make(map[string]bool),
+	}
+}
+
+// Replace swaps the whole index for one built from the given catalog
+func (idx *suggestIndex) Replace(bicycles []models.Bicycle, categories []models.Category) {
+	fresh := newSuggestIndex()
+	fresh.bulk = true
+	for _, bicycle := range bicycles {
+		fresh.putBicycle(bicycle)
+	}
+	for _, category := range categories {
+		fresh.putCategory(category)
+	}
+	fresh.bulk = false
+	fresh.rankSubtree(fresh.root)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.root = fresh.root
+	idx.entries = fresh.entries
+	idx.bicycles = fresh.bicycles
+}
+
+func (idx *suggestIndex) PutBicycle(bicycle models.Bicycle) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeBicycle(bicycle.ID.Hex())
+	idx.putBicycle(bicycle)
+}
+
+func (idx *suggestIndex) RemoveBicycle(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeBicycle(id)
+}
+
+func (idx *suggestIndex) PutCategory(category models.Category) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(SuggestionCategory + ":" + category.ID.Hex())
+	idx.putCategory(category)
+}
+
+func (idx *suggestIndex) RemoveCategory(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(SuggestionCategory + ":" + id)
+}
+
+// Search returns the best limit terms with a word starting with prefix, up to maxSuggestLimit
+func (idx *suggestIndex) Search(prefix string, limit int) []models.SearchSuggestion {
+	prefix = normalizeTerm(prefix)
+	results := []models.SearchSuggestion{}
+	if prefix == "" || limit < 1 {
+		return results
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	node := idx.root
+	for _, r := range prefix {
+		node = node.children[r]
+		if node == nil {
+			return results
+		}
+	}
+
+	if limit > len(node.top) {
+		limit = len(node.top)
+	}
+	for _, entry := range node.top[:limit] {
+		results = append(results, entry.suggestion)
+	}
+	return results
+}
+
+// ranksBefore orders matches: terms starting with the whole prefix first, then
+// brands shared by more bicycles, then shorter terms, then alphabetically
+func ranksBefore(a, b *suggestEntry, prefix string) bool {
+	aLeading, bLeading := strings.HasPrefix(a.normalized, prefix), strings.HasPrefix(b.normalized, prefix)
+	if aLeading != bLeading {
+		return aLeading
+	}
+	if a.refs != b.refs {
+		return a.refs > b.refs
+	}
+	if len(a.normalized) != len(b.normalized) {
+		return len(a.normalized) < len(b.normalized)
+	}
+	return a.normalized < b.normalized
+}
+
+// rank recomputes a node's cached matches from its own entries and its
+// children's cached matches, which together always contain the subtree's best
+func (idx *suggestIndex) rank(node *trieNode) {
+	top := make([]*suggestEntry, 0, maxSuggestLimit+1)
+	consider := func(entry *suggestEntry) {
+		for _, kept := range top {
+			if kept == entry {
+				return
+			}
+		}
+		if len(top) == maxSuggestLimit && !ranksBefore(entry, top[maxSuggestLimit-1], node.prefix) {
+			return
+		}
+		position := len(top)
+		for position > 0 && ranksBefore(entry, top[position-1], node.prefix) {
+			position--
+		}
+		top = append(top, nil)
+		copy(top[position+1:], top[position:])
+		top[position] = entry
+		if len(top) > maxSuggestLimit {
+			top = top[:maxSuggestLimit]
+		}
+	}
+
+	for key := range node.entries {
+		consider(idx.entries[key])
+	}
+	for _, child := range node.children {
+		for _, entry := range child.top {
+			consider(entry)
+		}
+	}
+	node.top = top
+}
+
+func (idx *suggestIndex) rankSubtree(node *trieNode) {
+	for _, child := range node.children {
+		idx.rankSubtree(child)
+	}
+	idx.rank(node)
+}
+
+// rankPaths re-ranks every node on the paths of a term, deepest first
+func (idx *suggestIndex) rankPaths(normalized string) {
+	if idx.bulk {
+		return
+	}
+	for _, start := range wordStarts(normalized) {
+		path := []*trieNode{idx.root}
+		node := idx.root
+		for _, r := range normalized[start:] {
+			node = node.children[r]
+			if node == nil {
+				break
+			}
+			path = append(path, node)
+		}
+		for i := len(path) - 1; i >= 0; i-- {
+			idx.rank(path[i])
+		}
+	}
+}
+
+func (idx *suggestIndex) putBicycle(bicycle models.Bicycle) {
+	id := bicycle.ID.Hex()
+	idx.bicycles[id] = models.Bicycle{ID: bicycle.ID, ModelName: bicycle.ModelName, Brand: bicycle.Brand}
+
+	idx.add(SuggestionModel+":"+id, models.SearchSuggestion{
+		Text: bicycle.ModelName,
+		Type: SuggestionModel,
+		ID:   id,
+	})
+
+	// Brands are shared, so they stay indexed while any bicycle uses them
+	if normalizeTerm(bicycle.Brand) != "" {
+		key := SuggestionBrand + ":" + normalizeTerm(bicycle.Brand)
+		if entry, exists := idx.entries[key]; exists {
+			entry.refs++
+			idx.rankPaths(entry.normalized)
+		} else {
+			idx.add(key, models.SearchSuggestion{
+				Text: bicycle.Brand,
+				Type: SuggestionBrand,
+			})
+		}
+	}
+}
+
+func (idx *suggestIndex) removeBicycle(id string) {
+	bicycle, exists := idx.bicycles[id]
+	if !exists {
+		return
+	}
+	delete(idx.bicycles, id)
+
+	idx.remove(SuggestionModel + ":" + id)
+
+	key := SuggestionBrand + ":" + normalizeTerm(bicycle.Brand)
+	if entry, exists := idx.entries[key]; exists {
+		entry.refs--
+		if entry.refs <= 0 {
+			idx.remove(key)
+		} else {
+			idx.rankPaths(entry.normalized)
+		}
+	}
+}
+
+func (idx *suggestIndex) putCategory(category models.Category) {
+	id := category.ID.Hex()
+	idx.add(SuggestionCategory+":"+id, models.SearchSuggestion{
+		Text: category.Name,
+		Type: SuggestionCategory,
+		ID:   id,
+	})
+}
+
+func (idx *suggestIndex) add(key string, suggestion models.SearchSuggestion) {
+	normalized := normalizeTerm(suggestion.Text)
+	if normalized == "" {
+		return
+	}
+
+	idx.entries[key] = &suggestEntry{
+		suggestion: suggestion,
+		normalized: normalized,
+		refs:       1,
+	}
+	for _, start := range wordStarts(normalized) {
+		node := idx.root
+		for _, r := range normalized[start:] {
+			child := node.children[r]
+			if child == nil {
+				child = newTrieNode(node.prefix + string(r))
+				node.children[r] = child
+			}
+			node = child
+		}
+		node.entries[key] = true
+	}
+	idx.rankPaths(normalized)
+}
+
+// remove unlinks an entry from the trie and prunes branches left empty
+func (idx *suggestIndex) remove(key string) {
+	entry, exists := idx.entries[key]
+	if !exists {
+		return
+	}
+	delete(idx.entries, key)
+
+	for _, start := range wordStarts(entry.normalized) {
+		removeFromTrie(idx.root, []rune(entry.normalized[start:]), key)
+	}
+	idx.rankPaths(entry.normalized)
+}
+
+// removeFromTrie deletes key at the end of path and reports whether node is now empty
+func removeFromTrie(node *trieNode, path []rune, key string) bool {
+	if len(path) == 0 {
+		delete(node.entries, key)
+	} else if child := node.children[path[0]]; child != nil {
+		if removeFromTrie(child, path[1:], key) {
+			delete(node.children, path[0])
+		}
+	}
+	return len(node.entries) == 0 && len(node.children) == 0
+}
+
+// wordStarts returns the byte offsets of each word in a normalized term
+func wordStarts(normalized string) []int {
+	starts := []int{0}
+	for i := 0; i < len(normalized); i++ {
+		if normalized[i] == ' ' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// normalizeTerm lowercases a term and collapses punctuation and whitespace to single spaces
+func normalizeTerm(text string) string {
+	return strings.Join(tokenize(text), " ")
+}
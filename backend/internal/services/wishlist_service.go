@@ -0,0 +1,237 @@
+package services
+
+import (
+	"bicycle-store/internal/models"
+	"bicycle-store/internal/repositories"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrWishlistFull is returned when adding to a wishlist of MaxWishlistItems bicycles
+	ErrWishlistFull = errors.New("wishlist is full")
+	// ErrInStock is returned when subscribing to a stock alert on a bicycle, or variant, that can be ordered
+	ErrInStock = errors.New("bicycle is in stock")
+	// ErrAlreadySubscribed is returned when the customer already waits for the bicycle
+	ErrAlreadySubscribed = errors.New("you will already be notified when this bicycle is back in stock")
+)
+
+type WishlistService struct {
+	customerRepo   *repositories.CustomerRepository
+	bicycleRepo    *repositories.BicycleRepository
+	stockAlertRepo *repositories.StockAlertRepository
+}
+
+func NewWishlistService() *WishlistService {
+	return &WishlistService{
+		customerRepo:   repositories.NewCustomerRepository(),
+		bicycleRepo:    repositories.NewBicycleRepository(),
+		stockAlertRepo: repositories.NewStockAlertRepository(),
+	}
+}
+
+// GetWishlist returns the customer's wishlisted bicycles that are in the catalog,
+// most recently added first
+func (s *WishlistService) GetWishlist(ctx context.Context, customerID primitive.ObjectID) ([]models.WishlistEntry, error) {
+	customer, err := s.customerRepo.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []models.WishlistEntry{}
+	if len(customer.Wishlist) == 0 {
+		return entries, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(customer.Wishlist))
+	for _, item := range customer.Wishlist {
+		ids = append(ids, item.BicycleID)
+	}
+
+	bicycles, err := s.bicycleRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]models.Bicycle, len(bicycles))
+	for _, bicycle := range bicycles {
+		byID[bicycle.ID] = bicycle
+	}
+
+	alerts, err := s.stockAlertRepo.GetWaitingBicycleIDs(ctx, customerID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := len(customer.Wishlist) - 1; i >= 0; i-- {
+		item := customer.Wishlist[i]
+		bicycle, ok := byID[item.BicycleID]
+		if !ok || !bicycle.IsPublic() {
+			continue
+		}
+		bicycle.ApplySale(now)
+		entries = append(entries, models.WishlistEntry{
+			Bicycle:    bicycle,
+			AddedAt:    item.AddedAt,
+			StockAlert: alerts[item.BicycleID],
+		})
+	}
+	return entries, nil
+}
+
+// AddToWishlist saves a public bicycle to the customer's wishlist; adding one
+// that is already there does nothing
+func (s *WishlistService) AddToWishlist(ctx context.Context, customerID primitive.ObjectID, bicycleID string) error {
+	bicID, err := primitive.ObjectIDFromHex(bicycleID)
+	if err != nil {
+		return errors.New("invalid bicycle ID")
+	}
+
+	bicycle, err := s.bicycleRepo.GetByID(ctx, bicID)
+	if err != nil {
+		return err
+	}
+	if !bicycle.IsPublic() {
+		return mongo.ErrNoDocuments
+	}
+
+	added, err := s.customerRepo.AddToWishlist(ctx, customerID, models.WishlistItem{
+		BicycleID: bicID,
+		AddedAt:   time.Now(),
+	})
+	if err != nil || added {
+		return err
+	}
+
+	customer, err := s.customerRepo.GetByID(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	for _, item := range customer.Wishlist {
+		if item.BicycleID == bicID {
+			return nil
+		}
+	}
+	return ErrWishlistFull
+}
+
+// RemoveFromWishlist removes a bicycle from the customer's wishlist
+func (s *WishlistService) RemoveFromWishlist(ctx context.Context, customerID, bicycleID primitive.ObjectID) error {
+	return s.customerRepo.RemoveFromWishlist(ctx, customerID, bicycleID)
+}
+
+// SubscribeStockAlert asks to notify the customer when a sold-out bicycle is back in
+// stock. Bicycles with variants are waited for per variant, given by sku.
+func (s *WishlistService) SubscribeStockAlert(ctx context.Context, customerID, bicycleID primitive.ObjectID, sku string) error {
+	bicycle, err := s.bicycleRepo.GetByID(ctx, bicycleID)
+	if err != nil {
+		return err
+	}
+	if !bicycle.IsPublic() {
+		return mongo.ErrNoDocuments
+	}
+
+	stock := bicycle.StockQuantity
+	if len(bicycle.Variants) > 0 {
+		if sku == "" {
+			return errors.New("bicycle has variants, specify the sku to wait for")
+		}
+		variant := bicycle.FindVariantBySKU(sku)
+		if variant == nil {
+			return errors.New("variant not found: " + sku)
+		}
+		stock = variant.StockQuantity
+	} else if sku != "" {
+		return errors.New("bicycle has no variants")
+	}
+	if stock > 0 {
+		return ErrInStock
+	}
+
+	err = s.stockAlertRepo.Create(ctx, &models.StockAlert{
+		BicycleID:  bicycleID,
+		SKU:        sku,
+		CustomerID: customerID,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAlreadySubscribed
+	}
+	if err != nil {
+		return err
+	}
+
+	// The bicycle may have been restocked just before the alert was saved
+	queueStockAlerts(ctx, s.stockAlertRepo, bicycleID, []string{sku})
+	return nil
+}
+
+// UnsubscribeStockAlert cancels the customer's waiting alert on a bicycle, or on its variant with the given SKU
+func (s *WishlistService) UnsubscribeStockAlert(ctx context.Context, customerID, bicycleID primitive.ObjectID, sku string) error {
+	return s.stockAlertRepo.Delete(ctx, bicycleID, customerID, sku)
+}
+
+// QueueStockAlerts queues the waiting alerts of every bicycle that is back in stock,
+// catching alerts whose queueing failed after a stock increase. Returns how many
+// notifications were queued.
+func (s *WishlistService) QueueStockAlerts(ctx context.Context) (int, error) {
+	bicycleIDs, err := s.stockAlertRepo.GetBicyclesWithWaitingAlerts(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, id := range bicycleIDs {
+		n, err := s.stockAlertRepo.QueueBackInStock(ctx, id, nil)
+		if err != nil {
+			return queued, err
+		}
+		queued += n
+	}
+	return queued, nil
+}
+
+// queueStockAlerts queues the back-in-stock notifications of a bicycle after a stock
+// change took the given SKUs from 0 to positive (see restockedSKUs). The stock change is
+// already saved, so a failure is logged rather than returned; the periodic
+// QueueStockAlerts picks the alerts up later.
+func queueStockAlerts(ctx context.Context, repo *repositories.StockAlertRepository, bicycleID primitive.ObjectID, skus []string) {
+	if len(skus) == 0 {
+		return
+	}
+	queued, err := repo.QueueBackInStock(ctx, bicycleID, skus)
+	if err != nil {
+		log.Printf("Warning: Failed to queue stock alerts of bicycle %s: %v", bicycleID.Hex(), err)
+		return
+	}
+	if queued > 0 {
+		log.Printf("Queued %d back-in-stock notifications for bicycle %s", queued, bicycleID.Hex())
+	}
+}
+
+// restockedSKUs returns the SKUs of the variants of a bicycle that went from 0 to
+// positive stock between before and after, or "" when a bicycle without variants did
+func restockedSKUs(before, after *models.Bicycle) []string {
+	if len(after.Variants) == 0 {
+		if before.StockQuantity <= 0 && after.StockQuantity > 0 {
+			return []string{""}
+		}
+		return nil
+	}
+
+	previous := make(map[string]int, len(before.Variants))
+	for _, variant := range before.Variants {
+		previous[variant.SKU] = variant.StockQuantity
+	}
+	var skus []string
+	for _, variant := range after.Variants {
+		if previous[variant.SKU] <= 0 && variant.StockQuantity > 0 {
+			skus = append(skus, variant.SKU)
+		}
+	}
+	return skus
+}
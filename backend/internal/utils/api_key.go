@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const apiKeyPrefix = "bsk_"
+
+// GenerateAPIKey returns a new random API key and its public prefix.
+// The prefix identifies the key in listings without revealing the secret part.
+func GenerateAPIKey() (string, string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+
+	key := apiKeyPrefix + hex.EncodeToString(bytes)
+	return key, key[:len(apiKeyPrefix)+8], nil
+}
+
+// HashAPIKey hashes an API key for storage and lookup. API keys carry 256 bits
+// of randomness, so a fast hash is enough and keeps per-request lookups cheap.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
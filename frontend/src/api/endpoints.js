@@ -57,12 +57,105 @@ export const bicycleApi = {
         return api.delete(`/bicycles/${id}`)
     },
 
+    getReviews(id, params = {}) {
+        return api.get(`/bicycles/${id}/reviews`, { params })
+    },
+
     addReview(id, data) {
         return api.post(`/bicycles/${id}/reviews`, data)
     },
 
+    updateReview(id, reviewId, data) {
+        return api.put(`/bicycles/${id}/reviews/${reviewId}`, data)
+    },
+
+    deleteReview(id, reviewId) {
+        return api.delete(`/bicycles/${id}/reviews/${reviewId}`)
+    },
+
+    // Similar and frequently bought together bicycles
+    getRelated(id) {
+        return api.get(`/bicycles/${id}/related`)
+    },
+
+    // Side-by-side comparison of 2 to 4 bicycles
+    compare(ids) {
+        return api.get('/bicycles/compare', { params: { ids: ids.join(',') } })
+    },
+
+    markReviewHelpful(id, reviewId) {
+        return api.post(`/bicycles/${id}/reviews/${reviewId}/helpful`)
+    },
+
+    reportReview(id, reviewId, reason = '') {
+        return api.post(`/bicycles/${id}/reviews/${reviewId}/report`, { reason })
+    },
+
+    // Back-in-stock alerts on sold-out bicycles, or on one sold-out variant
+    subscribeStockAlert(id, sku) {
+        return api.post(`/bicycles/${id}/stock-alert`, null, { params: { sku } })
+    },
+
+    unsubscribeStockAlert(id, sku) {
+        return api.delete(`/bicycles/${id}/stock-alert`, { params: { sku } })
+    },
+
     updateStock(id, quantity) {
         return api.patch(`/bicycles/${id}/stock`, { quantity })
+    },
+
+    // Admin listing including drafts and archived bicycles
+    getAllAdmin(params = {}) {
+        return api.get('/admin/bicycles', { params })
+    },
+
+    restore(id) {
+        return api.post(`/admin/bicycles/${id}/restore`)
+    },
+
+    // Gallery images; file is a File from an <input type="file">
+    uploadImage(id, file, altText = '', primary = false) {
+        const form = new FormData()
+        form.append('image', file)
+        form.append('alt_text', altText)
+        form.append('primary', primary)
+        return api.post(`/bicycles/${id}/images`, form, {
+            headers: { 'Content-Type': 'multipart/form-data' }
+        })
+    },
+
+    updateImage(id, imageId, data) {
+        return api.patch(`/bicycles/${id}/images/${imageId}`, data)
+    },
+
+    reorderImages(id, imageIds) {
+        return api.put(`/bicycles/${id}/images/order`, { image_ids: imageIds })
+    },
+
+    deleteImage(id, imageId) {
+        return api.delete(`/bicycles/${id}/images/${imageId}`)
+    },
+
+    // Bulk catalog import of a CSV or NDJSON file, run as a background job
+    importCatalog(file, dryRun = false) {
+        const form = new FormData()
+        form.append('file', file)
+        form.append('dry_run', dryRun)
+        return api.post('/admin/bicycles/import', form, {
+            headers: { 'Content-Type': 'multipart/form-data' }
+        })
+    },
+
+    getImportJobs() {
+        return api.get('/admin/import-jobs')
+    },
+
+    getImportJob(id) {
+        return api.get(`/admin/import-jobs/${id}`)
+    },
+
+    exportCatalog(format = 'csv') {
+        return api.get('/admin/bicycles/export', { params: { format }, responseType: 'blob' })
     }
 }
 
@@ -107,6 +200,34 @@ export const customerApi = {
 
     removeAddress(type) {
         return api.delete(`/customers/addresses/${type}`)
+    },
+
+    // Bicycles related to the ones in the customer's orders
+    getRecommendations(params = {}) {
+        return api.get('/customers/me/recommendations', { params })
+    },
+
+    getWishlist() {
+        return api.get('/customers/me/wishlist')
+    },
+
+    addToWishlist(bicycleId) {
+        return api.post('/customers/me/wishlist', { bicycle_id: bicycleId })
+    },
+
+    removeFromWishlist(bicycleId) {
+        return api.delete(`/customers/me/wishlist/${bicycleId}`)
+    }
+}
+
+export const reviewApi = {
+    // Moderation queue (Admin)
+    getQueue(params = {}) {
+        return api.get('/admin/reviews', { params })
+    },
+
+    moderate(bicycleId, reviewId, status) {
+        return api.patch(`/admin/bicycles/${bicycleId}/reviews/${reviewId}`, { status })
     }
 }
 
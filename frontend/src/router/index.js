@@ -12,6 +12,11 @@ const routes = [
         name: 'BicycleDetail',
         component: () => import('../views/BicycleDetailPage.vue')
     },
+    {
+        path: '/compare',
+        name: 'Compare',
+        component: () => import('../views/ComparePage.vue')
+    },
     {
         path: '/cart',
         name: 'Cart',
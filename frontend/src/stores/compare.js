@@ -0,0 +1,41 @@
+import { defineStore } from 'pinia'
+import { ref, computed } from 'vue'
+
+// Bicycles picked for side-by-side comparison; the API compares 2 to 4
+export const MAX_COMPARE = 4
+
+export const useCompareStore = defineStore('compare', () => {
+    const ids = ref(JSON.parse(localStorage.getItem('compare') || '[]'))
+
+    const canCompare = computed(() => ids.value.length >= 2)
+
+    function saveToStorage() {
+        localStorage.setItem('compare', JSON.stringify(ids.value))
+    }
+
+    function has(id) {
+        return ids.value.includes(id)
+    }
+
+    // Returns false when the comparison is already full
+    function add(id) {
+        if (has(id)) return true
+        if (ids.value.length >= MAX_COMPARE) return false
+        ids.value.push(id)
+        saveToStorage()
+        return true
+    }
+
+    function remove(id) {
+        ids.value = ids.value.filter(other => other !== id)
+        saveToStorage()
+    }
+
+    return {
+        ids,
+        canCompare,
+        has,
+        add,
+        remove
+    }
+})